@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cswank/kcli/internal/output"
+	"github.com/cswank/kcli/pkg/kafka"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	browseCmd = kingpin.Command("browse", "browse kafka interactively (the default)").Default()
+
+	topicsCmd = kingpin.Command("topics", "print the topics")
+
+	partitionsCmd   = kingpin.Command("partitions", "print the partitions of a topic")
+	partitionsTopic = partitionsCmd.Arg("topic", "the topic").Required().String()
+
+	catCmd   = kingpin.Command("cat", "print the messages in a topic (use -p and -o to choose where to start)")
+	catTopic = catCmd.Arg("topic", "the topic").Required().String()
+	catCount = catCmd.Flag("count", "number of messages to print (0 prints them all)").Short('n').Int()
+
+	grepCmd     = kingpin.Command("grep", "print the messages in a topic that contain a pattern (use -p and -o to choose where to start)")
+	grepTopic   = grepCmd.Arg("topic", "the topic").Required().String()
+	grepPattern = grepCmd.Arg("pattern", "the string to look for").Required().String()
+)
+
+// run runs one of the non-interactive commands, which print to
+// stdout instead of starting the gui.
+func run(cmd string, cli kafka.Reader, msgs <-chan string) error {
+	defer cli.Close()
+
+	if msgs != nil {
+		go func() {
+			for msg := range msgs {
+				fmt.Fprintln(os.Stderr, msg)
+			}
+		}()
+	}
+
+	switch cmd {
+	case topicsCmd.FullCommand():
+		return printTopics(cli)
+	case partitionsCmd.FullCommand():
+		return printPartitions(cli, *partitionsTopic)
+	case catCmd.FullCommand():
+		return cat(cli, *catTopic, *catCount)
+	case grepCmd.FullCommand():
+		return grep(cli, *grepTopic, *grepPattern)
+	}
+
+	return fmt.Errorf("unknown command %s", cmd)
+}
+
+func printTopics(cli kafka.Reader) error {
+	topics, err := cli.GetTopics()
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(topics)
+	w, err := output.New(os.Stdout, *outputFmt, "topic")
+	if err != nil {
+		return err
+	}
+
+	for _, t := range topics {
+		if err := w.Write(t); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+func printPartitions(cli kafka.Reader, topic string) error {
+	partitions, err := cli.GetTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	w, err := output.New(os.Stdout, *outputFmt, "topic", "partition", "start", "end", "size")
+	if err != nil {
+		return err
+	}
+
+	for _, p := range partitions {
+		if err := w.Write(topic, p.Partition, p.Start, p.End, p.End-p.Start); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// cat prints up to n messages (or all of them if n is 0).
+func cat(cli kafka.Reader, topic string, n int) error {
+	partitions, err := getPartitions(cli, topic)
+	if err != nil {
+		return err
+	}
+
+	w, err := output.New(os.Stdout, *outputFmt, output.MessageColumns...)
+	if err != nil {
+		return err
+	}
+
+	remaining := int64(n)
+	for _, p := range partitions {
+		end := p.End
+		if n > 0 {
+			end = remaining
+		}
+
+		err := cli.Fetch(p, end, func(msg kafka.Message) {
+			printMessage(w, msg)
+			remaining--
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if n > 0 && remaining <= 0 {
+			break
+		}
+	}
+	return w.Close()
+}
+
+func grep(cli kafka.Reader, topic, pattern string) error {
+	partitions, err := getPartitions(cli, topic)
+	if err != nil {
+		return err
+	}
+
+	w, err := output.New(os.Stdout, *outputFmt, output.MessageColumns...)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range partitions {
+		err := cli.Fetch(p, p.End, func(msg kafka.Message) {
+			if strings.Contains(string(msg.Value), pattern) {
+				printMessage(w, msg)
+			}
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// getPartitions returns the partitions chosen by --partition
+// (all of them by default) starting at --offset.
+func getPartitions(cli kafka.Reader, topic string) ([]kafka.Partition, error) {
+	partitions, err := cli.GetTopic(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []kafka.Partition
+	var found bool
+	for _, p := range partitions {
+		if *partition > -1 && p.Partition != int32(*partition) {
+			continue
+		}
+		found = true
+
+		if *offset > -1 {
+			// there is nothing to print in a partition that
+			// ends before the offset (and kafka would say it's
+			// out of range).
+			if p.End <= int64(*offset) {
+				continue
+			}
+
+			p.Offset = int64(*offset)
+			if p.Offset < p.Start {
+				p.Offset = p.Start
+			}
+		}
+		out = append(out, p)
+	}
+
+	if *partition > -1 && !found {
+		return nil, fmt.Errorf("topic %s has no partition %d", topic, *partition)
+	}
+
+	return out, nil
+}
+
+func printMessage(w *output.Writer, msg kafka.Message) {
+	if msg.DecodeError != "" {
+		fmt.Fprintf(os.Stderr, "partition %d offset %d: decode error: %s\n", msg.Partition.Partition, msg.Offset, msg.DecodeError)
+	}
+
+	if msg.KeyDecodeError != "" {
+		fmt.Fprintf(os.Stderr, "partition %d offset %d: key decode error: %s\n", msg.Partition.Partition, msg.Offset, msg.KeyDecodeError)
+	}
+
+	if err := w.Message(msg); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+//go:build wasip1
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// This is an example of how to create a WebAssembly decoder.
+// It turns the csv messages that dev/gen writes to the 'items'
+// topic into json.
+// To compile (go 1.24 or greater):
+//     GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o items.wasm main.go
+// Then start kcli like:
+//     kcli -d ./items.wasm
+
+// buffers keeps memory handed to kcli reachable until kcli
+// calls free.
+var buffers = map[uint32][]byte{}
+
+//go:wasmexport alloc
+func alloc(size uint32) uint32 {
+	b := make([]byte, size)
+	ptr := uint32(uintptr(unsafe.Pointer(&b[0])))
+	buffers[ptr] = b
+	return ptr
+}
+
+//go:wasmexport free
+func free(ptr, _ uint32) {
+	delete(buffers, ptr)
+}
+
+//go:wasmexport decode
+func decode(topicPtr, topicLen, dataPtr, dataLen uint32) uint64 {
+	topic := string(buffers[topicPtr][:topicLen])
+	data := buffers[dataPtr][:dataLen]
+
+	var flag uint32
+	out, err := items(topic, data)
+	if err != nil {
+		out = []byte(err.Error())
+		flag = 1 << 31
+	}
+
+	if len(out) == 0 {
+		return 0
+	}
+
+	ptr := alloc(uint32(len(out)))
+	copy(buffers[ptr], out)
+	return uint64(ptr)<<32 | uint64(uint32(len(out))|flag)
+}
+
+func items(topic string, b []byte) ([]byte, error) {
+	if topic != "items" {
+		return b, nil
+	}
+
+	parts := strings.Split(string(b), ",")
+	if len(parts) != 3 {
+		return nil, errors.New("expected first_name,last_name,age")
+	}
+
+	age, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"first_name": parts[0],
+		"last_name":  parts[1],
+		"age":        age,
+	})
+}
+
+func main() {}
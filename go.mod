@@ -6,11 +6,17 @@ require (
 	github.com/Shopify/sarama v1.24.1
 	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751 // indirect
 	github.com/alecthomas/units v0.0.0-20190924025748-f65c72e2690d // indirect
+	github.com/eapache/go-xerial-snappy v0.0.0-20180814174437-776d5712da21
 	github.com/fatih/color v1.7.0
 	github.com/jroimartin/gocui v0.4.0
+	github.com/klauspost/compress v1.8.2
 	github.com/mattn/go-colorable v0.1.4 // indirect
 	github.com/mattn/go-isatty v0.0.11 // indirect
-	github.com/mattn/go-runewidth v0.0.7 // indirect
+	github.com/mattn/go-runewidth v0.0.7
 	github.com/nsf/termbox-go v0.0.0-20190817171036-93860e161317 // indirect
+	github.com/tetratelabs/wazero v1.1.0
+	golang.org/x/crypto v0.24.0
+	golang.org/x/net v0.25.0
+	golang.org/x/term v0.21.0
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
 )
@@ -0,0 +1,105 @@
+//Package fuzzy matches and ranks names (topics) against a
+//pattern that only needs to contain some of the name's
+//characters, in order.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	exact       = 1 << 20
+	consecutive = 5
+	boundary    = 3
+	substring   = 10
+)
+
+//Find returns the items that match pattern, best match first.
+func Find(pattern string, items []string) []string {
+	type match struct {
+		item  string
+		score int
+	}
+
+	var matches []match
+	for _, item := range items {
+		if s := Score(pattern, item); s >= 0 {
+			matches = append(matches, match{item: item, score: s})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if len(a.item) != len(b.item) {
+			return len(a.item) < len(b.item)
+		}
+		return a.item < b.item
+	})
+
+	out := make([]string, len(matches))
+	for i, m := range matches {
+		out[i] = m.item
+	}
+	return out
+}
+
+//Score returns how well item matches pattern (ignoring case), or
+//-1 if it doesn't.  Characters that match one after another or at
+//the start of a word (after a '.', '-', '_' or '/') score higher,
+//and a pattern that is a substring of item beats one that isn't.
+func Score(pattern, item string) int {
+	p := []rune(strings.ToLower(pattern))
+	s := []rune(strings.ToLower(item))
+	if len(p) == 0 {
+		return 0
+	}
+
+	if string(p) == string(s) {
+		return exact
+	}
+
+	var score, j int
+	prev := -2
+	for i, r := range s {
+		if j == len(p) {
+			break
+		}
+
+		if r != p[j] {
+			continue
+		}
+
+		score++
+		if i == prev+1 {
+			score += consecutive
+		}
+
+		if i == 0 || isSeparator(s[i-1]) {
+			score += boundary
+		}
+
+		prev = i
+		j++
+	}
+
+	if j < len(p) {
+		return -1
+	}
+
+	if i := strings.Index(string(s), string(p)); i > -1 {
+		score += substring * len(p)
+		if i == 0 {
+			score += substring
+		}
+	}
+
+	return score
+}
+
+func isSeparator(r rune) bool {
+	return strings.ContainsRune(".-_/ ", r)
+}
@@ -0,0 +1,101 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pattern string
+		items   []string
+		want    []string
+	}{
+		{
+			name:    "exact match first",
+			pattern: "orders",
+			items:   []string{"billing.orders.created", "users.orders", "orders", "ordersx"},
+			want:    []string{"orders", "ordersx", "users.orders", "billing.orders.created"},
+		},
+		{
+			name:    "substring beats scattered",
+			pattern: "ord",
+			items:   []string{"o.r.d", "payments.ord"},
+			want:    []string{"payments.ord", "o.r.d"},
+		},
+		{
+			name:    "word starts beat the middle of words",
+			pattern: "uc",
+			items:   []string{"buzzcut", "users.created"},
+			want:    []string{"users.created", "buzzcut"},
+		},
+		{
+			name:    "ties go to the shorter name",
+			pattern: "log",
+			items:   []string{"c.log.x", "b.log"},
+			want:    []string{"b.log", "c.log.x"},
+		},
+		{
+			name:    "ties of the same length are alphabetical",
+			pattern: "log",
+			items:   []string{"c.log", "a.log", "b.log"},
+			want:    []string{"a.log", "b.log", "c.log"},
+		},
+		{
+			name:    "no match",
+			pattern: "zzz",
+			items:   []string{"orders", "users"},
+			want:    []string{},
+		},
+		{
+			name:    "out of order",
+			pattern: "sro",
+			items:   []string{"orders"},
+			want:    []string{},
+		},
+		{
+			name:    "ignores case",
+			pattern: "ORD",
+			items:   []string{"users", "Orders", "orders"},
+			want:    []string{"Orders", "orders"},
+		},
+		{
+			name:    "empty pattern matches everything",
+			pattern: "",
+			items:   []string{"b", "a"},
+			want:    []string{"a", "b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Find(tc.pattern, tc.items)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pattern string
+		item    string
+		want    int
+	}{
+		{name: "exact", pattern: "orders", item: "orders", want: exact},
+		{name: "exact ignoring case", pattern: "Orders", item: "oRDERS", want: exact},
+		{name: "no match", pattern: "x", item: "orders", want: -1},
+		{name: "pattern longer than item", pattern: "orderss", item: "orders", want: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Score(tc.pattern, tc.item); got != tc.want {
+				t.Errorf("got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
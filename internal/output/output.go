@@ -0,0 +1,238 @@
+//Package output prints rows of kafka data in a format that
+//other programs can read.  It backs both the non-interactive
+//commands and C-p in the gui.
+package output
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cswank/kcli/pkg/kafka"
+)
+
+const (
+	//Raw prints just the value of each message (or the only
+	//column), or a table for anything else.
+	Raw = "raw"
+	//JSON prints a single json array.
+	JSON = "json"
+	//JSONL prints one json object per line.
+	JSONL = "jsonl"
+	//CSV prints comma separated values with a header.
+	CSV = "csv"
+	//Table prints aligned columns with a header.
+	Table = "table"
+)
+
+var (
+	//Formats lists every format.
+	Formats = []string{Raw, JSON, JSONL, CSV, Table}
+
+	//MessageColumns are the columns written for each message.
+	MessageColumns = []string{"topic", "partition", "offset", "timestamp", "key", "value"}
+)
+
+//Writer writes rows with the same columns in one of the
+//Formats.  Close must be called once all rows are written.
+type Writer struct {
+	w       io.Writer
+	format  string
+	columns []string
+	value   int
+	rows    int
+	csv     *csv.Writer
+	table   *tabwriter.Writer
+}
+
+//New returns a Writer that writes rows with columns to w.
+func New(w io.Writer, format string, columns ...string) (*Writer, error) {
+	out := &Writer{
+		w:       w,
+		format:  format,
+		columns: columns,
+		value:   -1,
+	}
+
+	for i, c := range columns {
+		if c == "value" || len(columns) == 1 {
+			out.value = i
+		}
+	}
+
+	switch format {
+	case Raw:
+		if out.value == -1 {
+			out.format = Table
+			out.table = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		}
+	case JSON, JSONL:
+	case CSV:
+		out.csv = csv.NewWriter(w)
+	case Table:
+		out.table = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	default:
+		return nil, fmt.Errorf("invalid output format %q, it should be one of %s", format, strings.Join(Formats, ", "))
+	}
+
+	return out, nil
+}
+
+//Write writes one row.  vals must be in the same order as the
+//Writer's columns.
+func (w *Writer) Write(vals ...interface{}) error {
+	defer func() { w.rows++ }()
+
+	switch w.format {
+	case Raw:
+		_, err := fmt.Fprintln(w.w, text(vals[w.value]))
+		return err
+	case JSON:
+		sep := ",\n"
+		if w.rows == 0 {
+			sep = "[\n"
+		}
+		if _, err := io.WriteString(w.w, sep); err != nil {
+			return err
+		}
+		return w.object(vals)
+	case JSONL:
+		if err := w.object(vals); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w.w, "\n")
+		return err
+	case CSV:
+		if w.rows == 0 {
+			if err := w.csv.Write(w.columns); err != nil {
+				return err
+			}
+		}
+		return w.csv.Write(w.strings(vals))
+	default:
+		if w.rows == 0 {
+			fmt.Fprintln(w.table, strings.Join(w.columns, "\t"))
+		}
+		//tabwriter takes 0xff for its escape character, so bytes
+		//that aren't utf-8 can't go into a table as they are.
+		row := w.strings(vals)
+		for i, s := range row {
+			row[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(strings.ToValidUTF8(s, "\uFFFD"))
+		}
+		_, err := fmt.Fprintln(w.table, strings.Join(row, "\t"))
+		return err
+	}
+}
+
+//Message writes a kafka message.  The Writer must have been
+//created with MessageColumns.
+func (w *Writer) Message(msg kafka.Message) error {
+	return w.Write(msg.Partition.Topic, msg.Partition.Partition, msg.Offset, msg.Timestamp, key(msg.Key), value(msg.Value))
+}
+
+//Close flushes anything that has been buffered.
+func (w *Writer) Close() error {
+	switch w.format {
+	case JSON:
+		end := "\n]\n"
+		if w.rows == 0 {
+			end = "[]\n"
+		}
+		_, err := io.WriteString(w.w, end)
+		return err
+	case CSV:
+		w.csv.Flush()
+		return w.csv.Error()
+	case Table:
+		return w.table.Flush()
+	}
+	return nil
+}
+
+//object writes vals as a json object with the keys in column
+//order.
+func (w *Writer) object(vals []interface{}) error {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, v := range vals {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, _ := json.Marshal(w.columns[i])
+		buf.Write(k)
+		buf.WriteByte(':')
+
+		if t, ok := v.(time.Time); ok && t.IsZero() {
+			v = nil
+		}
+
+		d, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(d)
+	}
+	buf.WriteByte('}')
+
+	_, err := w.w.Write(buf.Bytes())
+	return err
+}
+
+func (w *Writer) strings(vals []interface{}) []string {
+	out := make([]string, len(vals))
+	for i, v := range vals {
+		out[i] = text(v)
+	}
+	return out
+}
+
+//value keeps json messages as json in the json formats, anything
+//else becomes a string (see key).
+func value(val []byte) interface{} {
+	if json.Valid(val) {
+		return json.RawMessage(val)
+	}
+	return key(val)
+}
+
+//key is a string unless it isn't utf-8, which a json string
+//can't hold.
+func key(val []byte) interface{} {
+	if !utf8.Valid(val) {
+		return binary(val)
+	}
+	return string(val)
+}
+
+//binary is written as {"base64": "..."} in the json formats
+//so that it comes through unchanged.  Raw and csv write it as
+//it is and a table shows the bytes that aren't utf-8 as \uFFFD.
+type binary []byte
+
+func (b binary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func text(v interface{}) string {
+	switch t := v.(type) {
+	case json.RawMessage:
+		return string(t)
+	case binary:
+		return string(t)
+	case time.Time:
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/cswank/kcli/pkg/kafka"
+)
+
+var testMessages = []kafka.Message{
+	{
+		Partition: kafka.Partition{Topic: "orders", Partition: 1},
+		Offset:    7,
+		Timestamp: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Key:       []byte("k1"),
+		Value:     []byte(`{"id": 1}`),
+	},
+	{
+		Partition: kafka.Partition{Topic: "orders", Partition: 2},
+		Offset:    8,
+		Key:       []byte{0xff, 0x01},
+		Value:     []byte{0xfe, 'a'},
+	},
+}
+
+func TestMessage(t *testing.T) {
+	testCases := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: Raw,
+			want:   "{\"id\": 1}\n\xfea\n",
+		},
+		{
+			format: JSON,
+			want: `[
+{"topic":"orders","partition":1,"offset":7,"timestamp":"2020-01-02T03:04:05Z","key":"k1","value":{"id":1}},
+{"topic":"orders","partition":2,"offset":8,"timestamp":null,"key":{"base64":"/wE="},"value":{"base64":"/mE="}}
+]
+`,
+		},
+		{
+			format: JSONL,
+			want: `{"topic":"orders","partition":1,"offset":7,"timestamp":"2020-01-02T03:04:05Z","key":"k1","value":{"id":1}}
+{"topic":"orders","partition":2,"offset":8,"timestamp":null,"key":{"base64":"/wE="},"value":{"base64":"/mE="}}
+`,
+		},
+		{
+			format: CSV,
+			want: "topic,partition,offset,timestamp,key,value\n" +
+				"orders,1,7,2020-01-02T03:04:05Z,k1,\"{\"\"id\"\": 1}\"\n" +
+				"orders,2,8,,\xff\x01,\xfea\n",
+		},
+		{
+			format: Table,
+			want: "topic    partition   offset   timestamp              key   value\n" +
+				"orders   1           7        2020-01-02T03:04:05Z   k1    {\"id\": 1}\n" +
+				"orders   2           8                               \uFFFD\x01    \uFFFDa\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := New(&buf, tc.format, MessageColumns...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, msg := range testMessages {
+				if err := w.Message(msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEmptyJSON(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := New(&buf, JSON, MessageColumns...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := buf.String(), "[]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRawWithoutValue(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := New(&buf, Raw, "topic", "partitions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, row := range [][]interface{}{{"orders", 3}, {"users", 12}} {
+		if err := w.Write(row...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "topic    partitions\norders   3\nusers    12\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,212 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
+)
+
+var (
+	defaultKeys = []string{"~/.ssh/id_rsa", "~/.ssh/id_ecdsa", "~/.ssh/id_ed25519"}
+)
+
+//signers returns the keys from the ssh agent (if there is
+//one) followed by the keys in files.  The server is offered
+//them in that order, so an encrypted key's passphrase is only
+//asked for if none of the agent's keys work.
+func (t *Tunnel) signers(files []string) ([]ssh.Signer, error) {
+	out := append([]ssh.Signer{}, t.agentKeys...)
+
+	explicit := len(files) > 0
+	if !explicit {
+		files = defaultKeys
+	}
+
+	for _, f := range files {
+		s, err := t.key(expand(f))
+		if os.IsNotExist(err) && !explicit {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+
+	if len(out) == 0 {
+		return nil, errors.New("no ssh keys found, start ssh-agent or use --ssh-key")
+	}
+
+	return out, nil
+}
+
+func (t *Tunnel) key(pth string) (ssh.Signer, error) {
+	if s, ok := t.keys[pth]; ok {
+		return s, nil
+	}
+
+	pem, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := ssh.ParsePrivateKey(pem)
+	if e, ok := err.(*ssh.PassphraseMissingError); ok {
+		s, err = t.encrypted(pth, pem, e.PublicKey)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not load ssh key %s: %s", pth, err)
+	}
+
+	t.keys[pth] = s
+	return s, nil
+}
+
+//encrypted returns a key that is decrypted the first time the
+//server accepts it.  Without the public key (older key files
+//only have it in the .pub file next to them) there is no way
+//to offer it without decrypting it first.
+func (t *Tunnel) encrypted(pth string, pem []byte, pub ssh.PublicKey) (ssh.Signer, error) {
+	if pub == nil {
+		if d, err := ioutil.ReadFile(pth + ".pub"); err == nil {
+			pub, _, _, _, _ = ssh.ParseAuthorizedKey(d)
+		}
+	}
+
+	if pub == nil {
+		return t.decrypt(pth, pem)
+	}
+
+	return &encryptedKey{t: t, pth: pth, pem: pem, pub: pub}, nil
+}
+
+//decrypt asks for a key's passphrase.  This only happens
+//in Connect, before the gui has taken over the terminal,
+//so a key that is first needed after that is an error.
+func (t *Tunnel) decrypt(pth string, pem []byte) (ssh.Signer, error) {
+	if atomic.LoadInt32(&t.prompt) == 0 {
+		return nil, errors.New("key is encrypted and its passphrase can only be asked for when kcli starts (add it to ssh-agent)")
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("key is encrypted and there is no terminal to ask for its passphrase")
+	}
+
+	fmt.Fprintf(os.Stderr, "Enter passphrase for key '%s': ", pth)
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.ParsePrivateKeyWithPassphrase(pem, pass)
+}
+
+//encryptedKey is a key file that needs a passphrase.
+type encryptedKey struct {
+	t   *Tunnel
+	pth string
+	pem []byte
+	pub ssh.PublicKey
+
+	lock   sync.Mutex
+	signer ssh.AlgorithmSigner
+}
+
+func (k *encryptedKey) PublicKey() ssh.PublicKey {
+	return k.pub
+}
+
+func (k *encryptedKey) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return k.SignWithAlgorithm(rand, data, "")
+}
+
+//SignWithAlgorithm lets rsa keys use sha2 signatures, which
+//is all that newer servers accept.
+func (k *encryptedKey) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	s, err := k.get()
+	if err != nil {
+		return nil, err
+	}
+	return s.SignWithAlgorithm(rand, data, algorithm)
+}
+
+func (k *encryptedKey) get() (ssh.AlgorithmSigner, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.signer != nil {
+		return k.signer, nil
+	}
+
+	s, err := k.t.decrypt(k.pth, k.pem)
+	if err != nil {
+		return nil, fmt.Errorf("could not load ssh key %s: %s", k.pth, err)
+	}
+
+	as, ok := s.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, fmt.Errorf("could not load ssh key %s: it can't be used for signing", k.pth)
+	}
+
+	k.signer = as
+	return as, nil
+}
+
+//hostKeys checks servers against ~/.ssh/known_hosts.
+func hostKeys() (ssh.HostKeyCallback, error) {
+	pth := expand("~/.ssh/known_hosts")
+	cb, err := knownhosts.New(pth)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s (ssh to the host once to create it): %s", pth, err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := cb(hostname, remote, key)
+		if ke, ok := err.(*knownhosts.KeyError); ok && len(ke.Want) == 0 {
+			return fmt.Errorf("%s is not in %s, ssh to it once to add its host key", hostname, pth)
+		}
+		return err
+	}, nil
+}
+
+//agentKeys gets the keys from the ssh agent (if there is one).
+func agentKeys(a agent.Agent) []ssh.Signer {
+	if a == nil {
+		return nil
+	}
+
+	s, err := a.Signers()
+	if err != nil {
+		log.Printf("could not get keys from ssh agent: %s", err)
+	}
+	return s
+}
+
+func sshAgent() agent.Agent {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil
+	}
+
+	a, err := net.Dial("unix", sock)
+	if err != nil {
+		log.Printf("could not connect to ssh agent: %s", err)
+		return nil
+	}
+	return agent.NewClient(a)
+}
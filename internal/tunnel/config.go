@@ -0,0 +1,136 @@
+package tunnel
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//sshConfig holds the parts of ~/.ssh/config that are
+//needed to connect the same way ssh would.
+type sshConfig struct {
+	hosts []hostBlock
+}
+
+//hostBlock is one Host section.  Options are kept in order
+//with lower cased keys since ssh uses the first value it
+//finds for each option.
+type hostBlock struct {
+	patterns []string
+	options  [][2]string
+}
+
+//loadSSHConfig parses the ssh config file at pth.  A missing
+//file is the same as an empty one.
+func loadSSHConfig(pth string) (*sshConfig, error) {
+	cfg := &sshConfig{}
+	f, err := os.Open(pth)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// options before the first Host apply to every host
+	block := hostBlock{patterns: []string{"*"}}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, val := parseLine(scanner.Text())
+		switch key {
+		case "":
+		case "host":
+			cfg.hosts = append(cfg.hosts, block)
+			block = hostBlock{patterns: strings.Fields(val)}
+		case "match":
+			// Match blocks aren't supported, so make sure
+			// their options don't apply to anything.
+			cfg.hosts = append(cfg.hosts, block)
+			block = hostBlock{}
+		default:
+			block.options = append(block.options, [2]string{key, val})
+		}
+	}
+
+	cfg.hosts = append(cfg.hosts, block)
+	return cfg, scanner.Err()
+}
+
+//parseLine handles both 'Key value' and 'Key=value'.
+func parseLine(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", ""
+	}
+
+	i := strings.IndexAny(line, " \t=")
+	if i == -1 {
+		return strings.ToLower(line), ""
+	}
+
+	key := strings.ToLower(line[:i])
+	val := strings.TrimSpace(line[i:])
+	val = strings.TrimSpace(strings.TrimPrefix(val, "="))
+	return key, strings.Trim(val, `"`)
+}
+
+//get returns the first value of key that applies to host.
+func (c *sshConfig) get(host, key string) string {
+	vals := c.getAll(host, key)
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+//getAll returns every value of key that applies to host,
+//which is what ssh does for options like IdentityFile.
+func (c *sshConfig) getAll(host, key string) []string {
+	key = strings.ToLower(key)
+	var out []string
+	for _, b := range c.hosts {
+		if !b.matches(host) {
+			continue
+		}
+		for _, o := range b.options {
+			if o[0] == key {
+				out = append(out, o[1])
+			}
+		}
+	}
+	return out
+}
+
+func (b hostBlock) matches(host string) bool {
+	var match bool
+	for _, p := range b.patterns {
+		if strings.HasPrefix(p, "!") {
+			if ok, _ := path.Match(p[1:], host); ok {
+				return false
+			}
+			continue
+		}
+
+		if ok, _ := path.Match(p, host); ok {
+			match = true
+		}
+	}
+	return match
+}
+
+//expand replaces a leading ~ with the home directory.
+func expand(pth string) string {
+	if pth != "~" && !strings.HasPrefix(pth, "~/") {
+		return pth
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return pth
+	}
+	return filepath.Join(home, pth[1:])
+}
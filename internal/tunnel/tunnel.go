@@ -2,178 +2,434 @@ package tunnel
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/benburkert/dns"
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/agent"
 )
 
-type endpoint struct {
-	Host string
-	Port int
-}
+const (
+	maxRetries = 5
+	backoff    = 250 * time.Millisecond
+)
+
+//sshClient is shared by every broker connection that goes
+//through the same ssh server.  It reconnects if the session drops.
+type sshClient struct {
+	addr    string
+	connect func() (*ssh.Client, error)
+	report  func(error)
 
-func (e *endpoint) String() string {
-	return fmt.Sprintf("%s:%d", e.Host, e.Port)
+	lock       sync.Mutex
+	client     *ssh.Client
+	connecting *attempt
 }
 
-type connection struct {
-	local     string
-	sshServer string
-	remote    string
-	cfg       *ssh.ClientConfig
+//attempt is a connection to an ssh server that is under way.
+//client and err are set before done is closed.
+type attempt struct {
+	done   chan struct{}
+	client *ssh.Client
+	err    error
 }
 
 //Tunnel holds data needed to create one
 //ssh tunnel per host for port forwarding.
 type Tunnel struct {
-	user    string
-	sshPort int
-	addrs   []string
+	user     string
+	sshPort  int
+	sshHost  string
+	addrs    []string
+	keyFiles []string
+
+	lock      sync.Mutex
+	config    *sshConfig
+	agentKeys []ssh.Signer
+	keys      map[string]ssh.Signer
+	hostKeys  ssh.HostKeyCallback
+	clients   map[string]*sshClient
+	messages  chan string
+
+	//prompt is 1 while Connect is running, the only time a
+	//passphrase can be asked for.  It is atomic because keys
+	//are loaded with lock held.
+	prompt int32
+}
+
+//hop is one ssh server on the way to kafka.
+type hop struct {
+	addr string
+	cfg  *ssh.ClientConfig
+}
+
+// Opt is a func that sets an attribute on Tunnel
+type Opt func(*Tunnel)
+
+//New creates a new Tunnel.  If host is empty then each
+//kafka host is also the ssh server for its own tunnel,
+//otherwise every tunnel goes through host (a bastion).
+//User and port override what ~/.ssh/config says when they
+//are set.
+func New(user string, port int, host string, addrs []string, opts ...Opt) *Tunnel {
+	t := &Tunnel{
+		user:     user,
+		sshPort:  port,
+		sshHost:  host,
+		addrs:    addrs,
+		keys:     map[string]ssh.Signer{},
+		clients:  map[string]*sshClient{},
+		messages: make(chan string, 10),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// WithIdentityFiles is used to authenticate with private key
+// files in addition to (or instead of) the ssh agent.
+func WithIdentityFiles(files ...string) Opt {
+	return func(t *Tunnel) {
+		t.keyFiles = files
+	}
+}
+
+//Messages reports problems with the tunnels once they are
+//running, since by then there is no caller to return them to.
+func (t *Tunnel) Messages() <-chan string {
+	return t.messages
 }
 
-//New creates a new Tunnel
-func New(user string, port int, addrs []string) *Tunnel {
-	return &Tunnel{
-		user:    user,
-		sshPort: port,
-		addrs:   addrs,
+func (t *Tunnel) report(err error) {
+	log.Println(err)
+	select {
+	case t.messages <- fmt.Sprintf("tunnel error: %s", err):
+	default:
 	}
 }
 
-//Connect creates an ssh tunnel for each address
-//passed in.  It also creates a dns lookup for the local
-//net.DefaultResolver so that hostname resolves to
-//the localhost address of the ssh tunnel.
+//Connect connects to the ssh server for each address passed
+//in so that bad keys or host keys are reported (and
+//passphrases asked for) before the gui starts.  Kafka clients
+//should then connect with Dial, which never asks.  The
+//addresses returned are the ones kafka clients should connect
+//to.
 func (t *Tunnel) Connect() ([]string, error) {
-	out := make([]string, len(t.addrs))
-	zone := &dns.Zone{
-		TTL: 5 * time.Minute,
-		RRs: map[string][]dns.Record{},
+	cfg, err := loadSSHConfig(expand("~/.ssh/config"))
+	if err != nil {
+		return nil, err
+	}
+
+	hk, err := hostKeys()
+	if err != nil {
+		return nil, err
 	}
 
-	for i, addr := range t.addrs {
-		local, host, err := t.doConnect(addr)
+	t.config = cfg
+	t.hostKeys = hk
+	t.agentKeys = agentKeys(sshAgent())
+
+	atomic.StoreInt32(&t.prompt, 1)
+	defer atomic.StoreInt32(&t.prompt, 0)
+
+	for _, addr := range t.addrs {
+		c, err := t.forAddr(addr)
 		if err != nil {
 			return nil, err
 		}
 
-		zone.RRs[host] = t.localDNS()
-		out[i] = fmt.Sprintf("%s:%s", host, strings.Split(local, ":")[1])
+		if _, err := c.get(); err != nil {
+			return nil, err
+		}
+	}
+	return t.addrs, nil
+}
+
+//Dial connects to addr through an ssh server.  Kafka clients
+//connect to whatever brokers the cluster metadata advertises,
+//which can change at any time, so each connection is forwarded
+//when it is made.  The ssh server resolves addr, so it doesn't
+//matter that kafka hosts can't be resolved from here.
+func (t *Tunnel) Dial(network, addr string) (net.Conn, error) {
+	c, err := t.forAddr(addr)
+	if err != nil {
+		return nil, err
 	}
 
-	t.resolve(zone)
-	return out, nil
+	log.Printf("forwarding %s through %s", addr, c.addr)
+	return c.dial(network, addr)
 }
 
-func (t *Tunnel) localDNS() []dns.Record {
-	return []dns.Record{
-		&dns.A{A: net.IPv4(127, 0, 0, 1).To4()},
-		&dns.AAAA{AAAA: net.ParseIP("::1")},
+//forAddr returns the ssh client that addr is forwarded through.
+func (t *Tunnel) forAddr(addr string) (*sshClient, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote address: %s", err)
+	}
+
+	server := t.sshHost
+	if server == "" {
+		server = host
 	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.client(server)
 }
 
-func (t *Tunnel) resolve(zone *dns.Zone) {
-	mux := new(dns.ResolveMux)
-	mux.Handle(dns.TypeANY, zone.Origin, zone)
+//client returns the shared ssh client for host, which may
+//be an alias from ~/.ssh/config.  Everything needed to
+//connect is gathered here so that reconnects use the same
+//keys (and passphrases) as the first connection.
+func (t *Tunnel) client(host string) (*sshClient, error) {
+	if c, ok := t.clients[host]; ok {
+		return c, nil
+	}
 
-	client := &dns.Client{
-		Resolver: mux,
+	var hops []hop
+	jump := t.config.get(host, "ProxyJump")
+	if jump != "" && jump != "none" {
+		for _, j := range strings.Split(jump, ",") {
+			h, err := t.hop(j, false)
+			if err != nil {
+				return nil, err
+			}
+			hops = append(hops, h)
+		}
 	}
 
-	r := &net.Resolver{
-		PreferGo: true,
-		Dial:     client.Dial,
+	h, err := t.hop(host, true)
+	if err != nil {
+		return nil, err
 	}
+	hops = append(hops, h)
 
-	net.DefaultResolver = r
+	c := &sshClient{
+		addr:    h.addr,
+		report:  t.report,
+		connect: func() (*ssh.Client, error) { return t.dial(hops) },
+	}
+	t.clients[host] = c
+	return c, nil
 }
 
-//doConnect creates and starts a single ssh tunnel
-func (t *Tunnel) doConnect(addr string) (string, string, error) {
-	parts := strings.Split(addr, ":")
-	if len(parts) != 2 {
-		return "", "", fmt.Errorf("invalid remote address (no port): %s", addr)
+//hop works out how to connect to dest ([user@]host[:port])
+//from the flags and ~/.ssh/config.  The flags only apply to
+//the last hop, not to any ProxyJump hosts.
+func (t *Tunnel) hop(dest string, last bool) (hop, error) {
+	var user, port string
+	if i := strings.LastIndex(dest, "@"); i > -1 {
+		user, dest = dest[:i], dest[i+1:]
 	}
 
-	host := parts[0]
-	addrs, err := net.LookupHost(host)
-	fmt.Println("looked up", addrs, err)
-	if err != nil {
-		return "", "", err
+	host := dest
+	if h, p, err := net.SplitHostPort(dest); err == nil {
+		host, port = h, p
 	}
 
-	if len(addrs) == 0 {
-		return "", "", fmt.Errorf("couldn't look up host %s", host)
+	if last && t.user != "" {
+		user = t.user
+	}
+	if user == "" {
+		user = t.config.get(host, "User")
+	}
+	if user == "" {
+		user = os.Getenv("USER")
 	}
 
-	c := connection{
-		sshServer: fmt.Sprintf("%s:%d", addrs[0], t.sshPort),
-		remote:    fmt.Sprintf("%s:%s", addrs[0], parts[1]),
-		local:     "127.0.0.1:",
-		cfg: &ssh.ClientConfig{
-			User: t.user,
-			Auth: []ssh.AuthMethod{
-				sshAgent(),
-			},
-			HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-				return nil
-			},
-		},
+	if last && t.sshPort != 0 {
+		port = strconv.Itoa(t.sshPort)
 	}
-	local, err := c.start()
-	if err != nil {
-		return "", "", err
+	if port == "" {
+		port = t.config.get(host, "Port")
+	}
+	if port == "" {
+		port = "22"
 	}
 
-	return local, host, nil
-}
+	hostname := t.config.get(host, "HostName")
+	if hostname == "" {
+		hostname = host
+	}
+
+	files := t.config.getAll(host, "IdentityFile")
+	if last {
+		files = append(append([]string{}, t.keyFiles...), files...)
+	}
 
-func (c *connection) start() (string, error) {
-	listener, err := net.Listen("tcp", c.local)
+	signers, err := t.signers(files)
 	if err != nil {
-		return "", err
+		return hop{}, err
 	}
-	//defer listener.Close()
 
-	go func(listener net.Listener) {
-		conn, err := listener.Accept()
-		if err != nil {
-			log.Fatal(err)
+	return hop{
+		addr: net.JoinHostPort(hostname, port),
+		cfg: &ssh.ClientConfig{
+			User:            user,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+			HostKeyCallback: t.hostKeys,
+			Timeout:         10 * time.Second,
+		},
+	}, nil
+}
+
+//dial connects to the last hop by way of the others.
+func (t *Tunnel) dial(hops []hop) (*ssh.Client, error) {
+	var cli *ssh.Client
+	for _, h := range hops {
+		var conn net.Conn
+		var err error
+		if cli == nil {
+			d := net.Dialer{Timeout: h.cfg.Timeout}
+			conn, err = d.Dial("tcp", h.addr)
+		} else {
+			conn, err = cli.Dial("tcp", h.addr)
 		}
 
-		serverConn, err := ssh.Dial("tcp", c.sshServer, c.cfg)
 		if err != nil {
-			log.Fatal(err)
+			closeClient(cli)
+			return nil, err
 		}
 
-		remoteConn, err := serverConn.Dial("tcp", c.remote)
+		c, chans, reqs, err := ssh.NewClientConn(conn, h.addr, h.cfg)
 		if err != nil {
-			log.Fatal(err)
+			conn.Close()
+			closeClient(cli)
+			return nil, err
 		}
 
-		copyConn := func(writer, reader net.Conn) {
-			_, err := io.Copy(writer, reader)
-			if err != nil {
-				log.Fatalf("io.Copy error: %s", err)
-			}
+		next := ssh.NewClient(c, chans, reqs)
+		if cli != nil {
+			// close the jump host when the session that
+			// goes through it ends.
+			go func(jump *ssh.Client) {
+				next.Wait()
+				jump.Close()
+			}(cli)
+		}
+		cli = next
+	}
+	return cli, nil
+}
+
+func closeClient(cli *ssh.Client) {
+	if cli != nil {
+		cli.Close()
+	}
+}
+
+//dial opens a connection to remote through the ssh server.
+func (s *sshClient) dial(network, remote string) (net.Conn, error) {
+	cli, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := cli.Dial(network, remote)
+	if _, ok := err.(*ssh.OpenChannelError); err == nil || ok {
+		// an OpenChannelError means the ssh server couldn't
+		// reach remote, which reconnecting won't fix.
+		return conn, err
+	}
+
+	// the session went away without Wait noticing yet.
+	s.drop(cli)
+	cli, err = s.get()
+	if err != nil {
+		return nil, err
+	}
+	return cli.Dial(network, remote)
+}
+
+//get returns the current ssh session, connecting if there
+//isn't one.  Only one caller connects at a time and the
+//others wait for its result, but the lock isn't held while
+//connecting so that drop and the other ssh clients aren't
+//held up by the backoff.
+func (s *sshClient) get() (*ssh.Client, error) {
+	s.lock.Lock()
+	if s.client != nil {
+		defer s.lock.Unlock()
+		return s.client, nil
+	}
+
+	if a := s.connecting; a != nil {
+		s.lock.Unlock()
+		<-a.done
+		return a.client, a.err
+	}
+
+	a := &attempt{done: make(chan struct{})}
+	s.connecting = a
+	s.lock.Unlock()
+
+	a.client, a.err = s.retry()
+
+	s.lock.Lock()
+	s.connecting = nil
+	if a.err == nil {
+		s.client = a.client
+		go s.wait(a.client)
+	}
+	s.lock.Unlock()
+	close(a.done)
+	return a.client, a.err
+}
+
+//retry connects with backoff.
+func (s *sshClient) retry() (*ssh.Client, error) {
+	var err error
+	wait := backoff
+	for i := 0; i < maxRetries; i++ {
+		if i > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+
+		var cli *ssh.Client
+		cli, err = s.connect()
+		if err == nil {
+			return cli, nil
 		}
+		log.Printf("ssh connection to %s failed (attempt %d): %s", s.addr, i+1, err)
+	}
+
+	return nil, fmt.Errorf("could not connect to %s: %s", s.addr, err)
+}
+
+//wait reconnects as soon as a session drops so that the
+//next kafka request doesn't have to.
+func (s *sshClient) wait(cli *ssh.Client) {
+	err := cli.Wait()
+	if !s.drop(cli) {
+		return
+	}
 
-		go copyConn(conn, remoteConn)
-		go copyConn(remoteConn, conn)
-	}(listener)
-	return listener.Addr().String(), nil
+	s.report(fmt.Errorf("ssh connection to %s dropped (%v), reconnecting", s.addr, err))
+	if _, err := s.get(); err != nil {
+		s.report(err)
+	}
 }
 
-func sshAgent() ssh.AuthMethod {
-	if a, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK")); err == nil {
-		return ssh.PublicKeysCallback(agent.NewClient(a).Signers)
+//drop forgets cli if it is still the current session and
+//reports whether it was.
+func (s *sshClient) drop(cli *ssh.Client) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.client != cli {
+		return false
 	}
-	return nil
+
+	s.client = nil
+	cli.Close()
+	return true
 }
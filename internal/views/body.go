@@ -6,7 +6,7 @@ import (
 	"reflect"
 	"strings"
 
-	"github.com/cswank/kcli/internal/kafka"
+	"github.com/cswank/kcli/pkg/kafka"
 	ui "github.com/jroimartin/gocui"
 )
 
@@ -24,10 +24,15 @@ type body struct {
 	flashMessage chan<- string
 	view         *ui.View
 	searchVal    string
+	wrap         bool
+	tabs         []tab
+	tab          int
+	cli          kafka.Reader
+	update       func()
 }
 
-func newBody(cli *kafka.Client, w, h int, flashMessage chan string, opts ...func(*stack) error) (*body, error) {
-	r, err := newRoot(cli, w, h-2, flashMessage)
+func newBody(cli kafka.Reader, w, h int, flashMessage chan string, update func(), opts ...func(*stack) error) (*body, error) {
+	r, err := newRoot(cli, w, h-2, flashMessage, update)
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +46,9 @@ func newBody(cli *kafka.Client, w, h int, flashMessage chan string, opts ...func
 		coords:       coords{x1: -1, y1: 0, x2: w, y2: h - 1},
 		stack:        s,
 		flashMessage: flashMessage,
+		tabs:         []tab{{}},
+		cli:          cli,
+		update:       update,
 	}, err
 }
 
@@ -71,17 +79,25 @@ func (b *body) color(val, search string) string {
 		return c2(val)
 	}
 
-	end := len(val)
-	if end > b.width {
-		end = b.width
-	}
 	s1 := val[0:i]
 	s2 := val[i : i+len(search)]
-	s3 := val[i+len(search) : end]
+	s3 := val[i+len(search):]
 	return fmt.Sprintf("%s%s%s", c2(s1), c3(s2), c2(s3))
 }
 
 func (b *body) escape(g *ui.Gui, v *ui.View) (string, error) {
+	if r, ok := b.stack.top.(*root); ok {
+		_, cur := v.Cursor()
+		if row, ok := r.collapse(cur); ok {
+			return r.header(), v.SetCursor(0, row)
+		}
+
+		if r.filter != "" {
+			r.setFilter("")
+			return r.header(), v.SetCursor(0, 0)
+		}
+	}
+
 	b.stack.pop()
 	r := b.stack.top.row()
 	return b.stack.top.header(), v.SetCursor(0, r)
@@ -106,10 +122,38 @@ func (b *body) enter(g *ui.Gui, v *ui.View) (string, error) {
 		return "", err
 	}
 
+	if m, ok := f.(*message); ok && b.wrap && !m.hex {
+		m.toggleWrap()
+	}
+
 	b.stack.add(f)
 	return b.stack.top.header(), v.SetCursor(0, 0)
 }
 
+//setWrap sets whether messages are wrapped, starting with the
+//one on the screen.
+func (b *body) setWrap(w bool) {
+	b.wrap = w
+	if m, ok := b.stack.top.(*message); ok && m.wrap != w {
+		m.toggleWrap()
+	}
+}
+
+//gotoTopic goes back to the list of topics and opens topic t.
+func (b *body) gotoTopic(t string) error {
+	r := b.root()
+	f, err := newTopic(b.cli, t, r.width, r.height, b.flashMessage)
+	if err != nil {
+		return err
+	}
+
+	b.stack.feeders = b.stack.feeders[:1]
+	b.stack.top = r
+	b.stack.add(f)
+	b.searchVal = ""
+	return b.view.SetCursor(0, 0)
+}
+
 func (b *body) next(g *ui.Gui, v *ui.View) error {
 	_, cur := v.Cursor()
 	if cur < len(b.rows)-1 {
@@ -147,6 +191,75 @@ func (b *body) jump(i int64) error {
 	return b.stack.top.jump(i)
 }
 
+//getBodyCoords is where the body goes on a screen that is w
+//wide and h tall.  When split is true it shares the screen with
+//a preview: the left half if the screen is wide enough,
+//otherwise the top half.
+func getBodyCoords(w, h int, split bool) coords {
+	switch {
+	case split && w >= previewSide:
+		return coords{x1: -1, y1: 0, x2: w / 2, y2: h - 1}
+	case split:
+		return coords{x1: -1, y1: 0, x2: w, y2: h / 2}
+	default:
+		return coords{x1: -1, y1: 0, x2: w, y2: h - 1}
+	}
+}
+
+//resize moves the body to c and reflows every feeder on the
+//stack to fit.
+func (b *body) resize(c coords) error {
+	w := c.x2 - c.x1 - 1
+	b.coords = c
+	b.width = w
+	b.height = c.y2 - c.y1 - 1
+	if b.height < 1 {
+		b.height = 1
+	}
+
+	var cur int
+	if b.view != nil {
+		_, cur = b.view.Cursor()
+	}
+
+	cur, err := b.stack.resize(w, b.height, cur)
+	if err != nil {
+		return err
+	}
+
+	for i := range b.tabs {
+		if i == b.tab {
+			continue
+		}
+
+		t := &b.tabs[i]
+		if t.cursor, err = t.stack.resize(w, b.height, t.cursor); err != nil {
+			return err
+		}
+	}
+
+	if b.view == nil {
+		return nil
+	}
+	return b.view.SetCursor(0, cur)
+}
+
+func (b *body) filter(s string) error {
+	r, ok := b.stack.top.(*root)
+	if !ok {
+		return nil
+	}
+
+	r.setFilter(s)
+	return b.view.SetCursor(0, 0)
+}
+
+//root is the list of topics, which is always at the bottom
+//of the stack.
+func (b *body) root() *root {
+	return b.stack.feeders[0].(*root)
+}
+
 func (b *body) search(s string, cb func(int64, int64)) (int64, error) {
 	if err := b.view.SetCursor(0, 0); err != nil {
 		return -1, err
@@ -180,6 +293,26 @@ func (s *stack) add(f feeder) {
 	s.feeders = append(s.feeders, f)
 }
 
+//resize changes the size of every feeder in the stack and
+//returns where the cursor (at cur) has moved to.
+func (s *stack) resize(w, h, cur int) (int, error) {
+	for _, f := range s.feeders {
+		if f != s.top {
+			if _, err := f.resize(w, h, f.row()); err != nil {
+				return cur, err
+			}
+			continue
+		}
+
+		row, err := f.resize(w, h, cur)
+		if err != nil {
+			return cur, err
+		}
+		cur = row
+	}
+	return cur, nil
+}
+
 func (s *stack) pop() {
 	if len(s.feeders) == 1 {
 		return
@@ -197,18 +330,19 @@ func enterTopic(height int, t string) func(*stack) error {
 			return fmt.Errorf("unexpected feeder: %T", s.top)
 		}
 		i := -1
-		for j, topic := range r.topics {
-			if t == topic {
+		for j, item := range r.items {
+			if t == item.topic {
 				i = j
 				break
 			}
 		}
 
 		if i == -1 {
-			return fmt.Errorf("could not find partition '%s'", t)
+			return fmt.Errorf("could not find topic '%s'", t)
 		}
 
-		f, err := r.enter(i)
+		r.pg = i / r.height
+		f, err := r.enter(i % r.height)
 		s.add(f)
 		return err
 	}
@@ -216,6 +350,10 @@ func enterTopic(height int, t string) func(*stack) error {
 
 func enterPartitionOrOffset(height, i int) func(*stack) error {
 	return func(s *stack) error {
+		if p, ok := s.top.(*partition); ok {
+			return enterOffset(s, p, int64(i))
+		}
+
 		page := i / height
 		i = i - (page * height)
 		s.top.page(page)
@@ -228,3 +366,23 @@ func enterPartitionOrOffset(height, i int) func(*stack) error {
 		return nil
 	}
 }
+
+//enterOffset goes to the message at offset i.  Offsets can
+//have gaps so it can't be found by counting rows.
+func enterOffset(s *stack, p *partition, i int64) error {
+	if i >= p.partition.End {
+		return fmt.Errorf("offset %d is past the end of partition %d (%d)", i, p.partition.Partition, p.partition.End)
+	}
+
+	if err := p.jump(i); err != nil {
+		return err
+	}
+
+	f, err := p.enter(0)
+	if err != nil {
+		return err
+	}
+
+	s.add(f)
+	return nil
+}
@@ -0,0 +1,285 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cswank/kcli/internal/fuzzy"
+	"github.com/cswank/kcli/internal/output"
+	"github.com/cswank/kcli/pkg/kafka"
+)
+
+//command is something that can be typed after ':', like
+//':topic orders'.
+type command struct {
+	name string
+	run  func(args []string) error
+	//complete returns the possible values of the argument
+	//that starts with arg
+	complete func(arg string) []string
+	help     keyHelp
+}
+
+//decoderSetter is a kafka.Reader whose Decoder can be
+//changed (the mock can't).
+type decoderSetter interface {
+	SetDecoder(kafka.Decoder) kafka.Decoder
+}
+
+var (
+	settings = []string{"wrap", "nowrap", "preview", "nopreview"}
+)
+
+//runCommand runs what was typed on the command line.  Errors
+//are flashed instead of returned so that a typo doesn't end
+//the program.
+func (s *screen) runCommand(line string) error {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil
+	}
+
+	cmd, ok := s.getCommand(args[0])
+	if !ok {
+		s.flashMessage <- fmt.Sprintf("unknown command: %s", args[0])
+		return nil
+	}
+
+	if err := cmd.run(args[1:]); err != nil {
+		s.flashMessage <- fmt.Sprintf("%s: %s", cmd.name, err)
+	}
+	return nil
+}
+
+func (s *screen) getCommand(name string) (command, bool) {
+	for _, cmd := range s.commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+//complete returns the ways that line could be finished: the
+//names of the commands if it is still the first word,
+//otherwise whatever the command suggests for its argument.
+func (s *screen) complete(line string) []string {
+	i := strings.Index(line, " ")
+	if i == -1 {
+		var out []string
+		for _, cmd := range s.commands {
+			if strings.HasPrefix(cmd.name, line) {
+				out = append(out, cmd.name+" ")
+			}
+		}
+		return out
+	}
+
+	cmd, ok := s.getCommand(line[:i])
+	if !ok || cmd.complete == nil {
+		return nil
+	}
+
+	arg := strings.TrimLeft(line[i:], " ")
+	var out []string
+	for _, c := range cmd.complete(arg) {
+		out = append(out, fmt.Sprintf("%s %s", cmd.name, c))
+	}
+	return out
+}
+
+func (s *screen) topicNames(arg string) []string {
+	var out []string
+	for _, t := range s.body.root().all {
+		if strings.HasPrefix(t, arg) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func (s *screen) settingNames(arg string) []string {
+	var out []string
+	for _, o := range settings {
+		if strings.HasPrefix(o, arg) {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+//topicCommand goes to a topic.  Like --topic a name that
+//doesn't match exactly is fuzzy matched.
+func (s *screen) topicCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("which topic?")
+	}
+
+	all := s.body.root().all
+	t := args[0]
+	for _, name := range all {
+		if name == t {
+			return s.body.gotoTopic(t)
+		}
+	}
+
+	matches := fuzzy.Find(t, all)
+	if len(matches) == 0 {
+		return fmt.Errorf("no topic matches %s", t)
+	}
+	return s.body.gotoTopic(matches[0])
+}
+
+func (s *screen) jumpCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("jump to where?")
+	}
+
+	n, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	return s.body.jump(n)
+}
+
+//exportCommand writes what is in the current view to a file,
+//like C-p does to stdout.  The format comes from the file's
+//extension (export orders.csv) and is --output otherwise.
+//Writing a whole partition can take a while so it is done in
+//the background (like a search) with progress in the footer.
+func (s *screen) exportCommand(args []string) error {
+	return s.exportTo(args, os.O_EXCL)
+}
+
+//overwriteCommand is :export! which, like vi's :w!, replaces
+//the file if it is already there.
+func (s *screen) overwriteCommand(args []string) error {
+	return s.exportTo(args, os.O_TRUNC)
+}
+
+func (s *screen) exportTo(args []string, flag int) error {
+	if len(args) != 1 {
+		return errors.New("export to which file?")
+	}
+
+	format := s.output
+	ext := strings.TrimPrefix(filepath.Ext(args[0]), ".")
+	for _, f := range output.Formats {
+		if f == ext {
+			format = f
+		}
+	}
+
+	f, err := os.OpenFile(args[0], os.O_CREATE|os.O_WRONLY|flag, 0666)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists (use :export! to overwrite it)", args[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.lock = true
+	go s.export(s.body.stack.top, f, format)
+	return nil
+}
+
+func (s *screen) export(top feeder, f *os.File, format string) {
+	defer func() { s.lock = false }()
+
+	w := &lineCounter{w: f, every: 1000, report: func(n int) {
+		s.flashMessage <- fmt.Sprintf("writing %s: %d rows", f.Name(), n)
+	}}
+
+	err := top.print(w, log.Writer(), format)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		s.flashMessage <- fmt.Sprintf("export: %s", err)
+		return
+	}
+
+	s.flashMessage <- fmt.Sprintf("wrote %s (%d rows)", f.Name(), w.n)
+}
+
+//lineCounter calls report every so many lines written to w.
+type lineCounter struct {
+	w      io.Writer
+	n      int
+	every  int
+	report func(int)
+}
+
+func (l *lineCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b != '\n' {
+			continue
+		}
+
+		l.n++
+		if l.n%l.every == 0 {
+			l.report(l.n)
+		}
+	}
+	return l.w.Write(p)
+}
+
+//decoderCommand changes the Decoder that messages are run
+//through ('none' goes back to no decoding).
+func (s *screen) decoderCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("which decoder?")
+	}
+
+	ds, ok := s.client.(decoderSetter)
+	if !ok || s.loadDecoder == nil {
+		return errors.New("the decoder can't be changed")
+	}
+
+	var dec kafka.Decoder
+	if args[0] != "none" {
+		var err error
+		if dec, err = s.loadDecoder(args[0]); err != nil {
+			return err
+		}
+	}
+
+	// a wasm decoder has a runtime that has to be shut down.
+	if c, ok := ds.SetDecoder(dec).(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Printf("could not close the old decoder: %s", err)
+		}
+	}
+	if p, ok := s.body.stack.top.(*partition); ok {
+		if err := p.fetch(p.partition.Offset); err != nil {
+			return err
+		}
+	}
+
+	s.flashMessage <- fmt.Sprintf("decoding messages with %s", args[0])
+	return nil
+}
+
+func (s *screen) setCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("set what? (%s)", strings.Join(settings, ", "))
+	}
+
+	switch args[0] {
+	case "wrap", "nowrap":
+		s.body.setWrap(args[0] == "wrap")
+	case "preview", "nopreview":
+		s.preview.on = args[0] == "preview"
+	default:
+		return fmt.Errorf("unknown option %s (%s)", args[0], strings.Join(settings, ", "))
+	}
+	return nil
+}
@@ -8,14 +8,19 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/cswank/kcli/internal/colors"
-	"github.com/cswank/kcli/internal/kafka"
+	"github.com/cswank/kcli/internal/fuzzy"
+	"github.com/cswank/kcli/internal/output"
+	"github.com/cswank/kcli/pkg/kafka"
 )
 
 //feeder feeds the screen the data that it craves
 type feeder interface {
-	print()
+	//print writes everything in the feeder to out.  Anything
+	//that went wrong with a message goes to errs.
+	print(out, errs io.Writer, format string) error
 	getRows() ([]string, error)
 	page(page int) error
 	header() string
@@ -23,19 +28,32 @@ type feeder interface {
 	jump(i int64) error
 	search(s string, cb func(int64, int64)) (int64, error)
 	row() int
+	//resize changes the size of the pages and returns where
+	//the cursor (at row) has to move to so that it stays on the
+	//same item.
+	resize(width, height, row int) (int, error)
 }
 
 type root struct {
-	cli          *kafka.Client
+	cli          kafka.Reader
 	width        int
 	height       int
+	all          []string
 	topics       []string
+	items        []rootItem
+	filter       string
+	showInfo     bool
+	hideInternal bool
+	tree         bool
+	separator    string
+	expanded     map[string]bool
+	info         *topicInfo
 	enteredAt    int
 	pg           int
 	flashMessage chan<- string
 }
 
-func newRoot(cli *kafka.Client, width, height int, flashMessage chan<- string) (*root, error) {
+func newRoot(cli kafka.Reader, width, height int, flashMessage chan<- string, update func()) (*root, error) {
 	topics, err := cli.GetTopics()
 	if len(topics) == 0 {
 		return nil, fmt.Errorf("no topics found in kafka")
@@ -46,42 +64,243 @@ func newRoot(cli *kafka.Client, width, height int, flashMessage chan<- string) (
 		cli:          cli,
 		width:        width,
 		height:       height,
+		all:          topics,
 		topics:       topics,
+		items:        listItems(topics),
+		separator:    ".",
+		expanded:     map[string]bool{},
+		info:         newTopicInfo(cli, update),
 		flashMessage: flashMessage,
 	}, err
 }
 
-func (r *root) print() {
-	fmt.Println(r.header())
+//setFilter narrows the topics down to the ones that fuzzy
+//match f, best match first.
+func (r *root) setFilter(f string) {
+	r.filter = f
+	r.refresh()
+}
+
+//toggleInternal hides (or shows again) topics like
+//__consumer_offsets and _schemas.
+func (r *root) toggleInternal() {
+	r.hideInternal = !r.hideInternal
+	r.refresh()
+}
+
+func (r *root) toggleInfo() {
+	r.showInfo = !r.showInfo
+}
+
+//toggleTree switches between a flat list of topics and a tree
+//of topics grouped by the parts of their names.
+func (r *root) toggleTree() {
+	r.tree = !r.tree
+	r.refresh()
+}
+
+func (r *root) refresh() {
+	r.pg = 0
+	topics := r.all
+	if r.hideInternal {
+		topics = nil
+		for _, t := range r.all {
+			if !strings.HasPrefix(t, "_") {
+				topics = append(topics, t)
+			}
+		}
+	}
+
+	if r.filter != "" {
+		topics = fuzzy.Find(r.filter, topics)
+	}
+	r.topics = topics
+	r.build()
+}
+
+func (r *root) build() {
+	if !r.tree {
+		r.items = listItems(r.topics)
+		return
+	}
+
+	// every group is open while filtering so the matches can
+	// be seen
+	r.items = treeItems(r.topics, "", r.separator, 0, func(g string) bool {
+		return r.filter != "" || r.expanded[g]
+	})
+}
+
+//collapse closes the group at row (or the group that the
+//row is in) and returns the row that the group is now on.
+func (r *root) collapse(row int) (int, bool) {
+	i := r.pg*r.height + row
+	if !r.tree || r.filter != "" || i >= len(r.items) {
+		return 0, false
+	}
+
+	item := r.items[i]
+	group := item.parent
+	if item.isGroup() && r.expanded[item.group] {
+		group = item.group
+	}
+
+	if group == "" {
+		return 0, false
+	}
+
+	r.expanded[group] = false
+	r.build()
+	for j, item := range r.items {
+		if item.group == group {
+			r.pg = j / r.height
+			return j % r.height, true
+		}
+	}
+	return 0, true
+}
+
+func (r *root) print(out, errs io.Writer, format string) error {
+	if !r.showInfo {
+		w, err := output.New(out, format, "topic")
+		if err != nil {
+			return err
+		}
+
+		for _, t := range r.topics {
+			if err := w.Write(t); err != nil {
+				return err
+			}
+		}
+		return w.Close()
+	}
+
+	w, err := output.New(out, format, "topic", "partitions", "messages", "replicas")
+	if err != nil {
+		return err
+	}
+
 	for _, t := range r.topics {
-		fmt.Println(t)
+		info, err := r.info.wait(t)
+		if err != nil {
+			return err
+		}
+
+		if err := w.Write(t, info.Partitions, info.Messages, info.Replicas); err != nil {
+			return err
+		}
 	}
+	return w.Close()
 }
 
 func (r *root) page(pg int) error {
-	if (r.pg == 0 && pg < 0) || (r.pg+pg)*r.height > len(r.topics) {
+	if (r.pg == 0 && pg < 0) || (r.pg+pg)*r.height >= len(r.items) {
 		return nil
 	}
 	r.pg += pg
 	return nil
 }
 
-func (r *root) getRows() ([]string, error) {
+func (r *root) chunk() []rootItem {
 	start := r.pg * r.height
 	end := r.pg*r.height + r.height
-	if end >= len(r.topics) {
-		end = len(r.topics)
+	if end >= len(r.items) {
+		end = len(r.items)
 	}
-	return r.topics[start:end], nil
+	return r.items[start:end]
+}
+
+func (r *root) getRows() ([]string, error) {
+	chunk := r.chunk()
+	out := make([]string, len(chunk))
+	if !r.showInfo {
+		for i, item := range chunk {
+			out[i] = item.label
+		}
+		return out, nil
+	}
+
+	w := r.nameWidth()
+	for i, item := range chunk {
+		partitions, messages, replicas := r.counts(item)
+		label := pad(clip(item.label, 0, w), w)
+		out[i] = fmt.Sprintf("%s %10s %14s %8s", label, partitions, messages, replicas)
+	}
+	return out, nil
+}
+
+//counts adds up the info of every topic in item.  Replicas
+//is only shown for a group if all of its topics agree.
+func (r *root) counts(item rootItem) (string, string, string) {
+	var partitions int
+	var messages int64
+	replicas := -1
+	for _, t := range item.topics {
+		res := r.info.get(t)
+		if res.err != nil {
+			return "?", "?", "?"
+		}
+
+		if !res.done {
+			return "...", "...", "..."
+		}
+
+		partitions += res.info.Partitions
+		messages += res.info.Messages
+		if replicas == -1 || replicas == res.info.Replicas {
+			replicas = res.info.Replicas
+		} else {
+			replicas = -2
+		}
+	}
+
+	rf := fmt.Sprintf("%d", replicas)
+	if replicas == -2 {
+		rf = "mixed"
+	}
+	return fmt.Sprintf("%d", partitions), fmt.Sprintf("%d", messages), rf
+}
+
+//nameWidth is how wide the topic column is when the info
+//columns are shown.  It is 0 when the screen is too narrow
+//for anything but the info.
+func (r *root) nameWidth() int {
+	max := r.width - 36
+	if max < 0 {
+		max = 0
+	}
+
+	w := len("topics")
+	for _, item := range r.chunk() {
+		if tw := textWidth(item.label); tw > w {
+			w = tw
+		}
+	}
+
+	if w > max {
+		w = max
+	}
+	return w
 }
 
 func (r *root) enter(row int) (feeder, error) {
-	if row >= len(r.topics) {
-		r.flashMessage <- "nothing to see here"
+	i := r.pg*r.height + row
+	if i >= len(r.items) {
+		go func() { r.flashMessage <- "nothing to see here" }()
 		return nil, errNoData
 	}
+
+	item := r.items[i]
+	if item.isGroup() {
+		if r.filter == "" {
+			r.expanded[item.group] = true
+			r.build()
+		}
+		return nil, errNoData
+	}
+
 	r.enteredAt = row
-	return newTopic(r.cli, r.topics[row], r.width, r.height, r.flashMessage)
+	return newTopic(r.cli, item.topic, r.width, r.height, r.flashMessage)
 }
 
 func (r *root) jump(_ int64) error                                   { return nil }
@@ -89,24 +308,60 @@ func (r *root) search(_ string, _ func(int64, int64)) (int64, error) { return -1
 
 func (r *root) row() int { return r.enteredAt }
 
+func (r *root) resize(width, height, row int) (int, error) {
+	i := r.pg*r.height + row
+	r.width = width
+	r.height = height
+	r.pg = i / height
+	r.enteredAt = i % height
+	return r.enteredAt, nil
+}
+
 func (r *root) header() string {
-	return "topics"
+	h := "topics"
+	if r.filter != "" {
+		h = fmt.Sprintf("topics matching '%s' (%d of %d)", r.filter, len(r.topics), len(r.all))
+	}
+
+	if r.hideInternal {
+		h += " (hiding internal)"
+	}
+
+	if !r.showInfo {
+		return h
+	}
+	return fmt.Sprintf("%-*s %10s %14s %8s", r.nameWidth(), h, "partitions", "messages", "replicas")
 }
 
 type topic struct {
-	cli    *kafka.Client
+	cli    kafka.Reader
 	height int
 	width  int
 	offset int
 
 	topic        string
 	partitions   []kafka.Partition
+	sortBy       int
+	desc         bool
 	fmt          string
 	enteredAt    int
 	flashMessage chan<- string
 }
 
-func newTopic(cli *kafka.Client, t string, width, height int, flashMessage chan<- string) (feeder, error) {
+//partitionSorts are the columns that a topic's partitions
+//can be sorted by, in the order that 's' cycles through them.
+var partitionSorts = []struct {
+	name string
+	key  func(kafka.Partition) int64
+}{
+	{name: "partition", key: func(p kafka.Partition) int64 { return int64(p.Partition) }},
+	{name: "size", key: func(p kafka.Partition) int64 { return p.End - p.Start }},
+	{name: "1st offset", key: func(p kafka.Partition) int64 { return p.Start }},
+	{name: "current offset", key: func(p kafka.Partition) int64 { return p.Offset }},
+	{name: "last offset", key: func(p kafka.Partition) int64 { return p.End }},
+}
+
+func newTopic(cli kafka.Reader, t string, width, height int, flashMessage chan<- string) (feeder, error) {
 	partitions, err := cli.GetTopic(t)
 	return &topic{
 		cli:          cli,
@@ -125,23 +380,77 @@ func (t *topic) search(s string, cb func(int64, int64)) (int64, error) {
 		return -1, err
 	}
 	t.partitions = results
+	t.sort()
 
 	return int64(len(results)), nil
 }
 
+//jump moves to the partition with id i, wherever the current
+//sort has put it.
 func (t *topic) jump(i int64) error {
-	if int(i) >= len(t.partitions) || int(i) < 0 {
-		t.flashMessage <- "nothing to see here"
-		return nil
+	for j, p := range t.partitions {
+		if int64(p.Partition) == i {
+			t.offset = j
+			return nil
+		}
 	}
-	t.offset = int(i)
+
+	t.flashMessage <- "nothing to see here"
 	return nil
 }
 
+//nextSort sorts by the next column in partitionSorts.
+func (t *topic) nextSort() {
+	t.sortBy = (t.sortBy + 1) % len(partitionSorts)
+	t.offset = 0
+	t.sort()
+}
+
+func (t *topic) reverse() {
+	t.desc = !t.desc
+	t.offset = 0
+	t.sort()
+}
+
+func (t *topic) sort() {
+	key := partitionSorts[t.sortBy].key
+	sort.SliceStable(t.partitions, func(i, j int) bool {
+		a, b := t.partitions[i], t.partitions[j]
+		ka, kb := key(a), key(b)
+		if ka == kb {
+			return a.Partition < b.Partition
+		}
+		if t.desc {
+			return ka > kb
+		}
+		return ka < kb
+	})
+}
+
 func (t *topic) row() int { return t.enteredAt }
 
+func (t *topic) resize(width, height, row int) (int, error) {
+	t.width = width
+	t.height = height
+	if row >= height {
+		t.offset += row
+		row = 0
+	}
+	t.enteredAt = row
+	return row, nil
+}
+
 func (t *topic) header() string {
-	return "partition     1st offset             current offset         last offset            size"
+	h := "partition     1st offset             current offset         last offset            size"
+	if t.sortBy == 0 && !t.desc {
+		return h
+	}
+
+	dir := "ascending"
+	if t.desc {
+		dir = "descending"
+	}
+	return fmt.Sprintf("%s    (by %s, %s)", h, partitionSorts[t.sortBy].name, dir)
 }
 
 func (t *topic) setOffset(n int64) error {
@@ -164,6 +473,7 @@ func (t *topic) setOffset(n int64) error {
 		}
 		t.partitions[i] = part
 	}
+	t.sort()
 	return nil
 }
 
@@ -209,27 +519,33 @@ func (t *topic) enter(row int) (feeder, error) {
 	return newPartition(t.cli, p, t.width, t.height, t.flashMessage)
 }
 
-func (t *topic) print() {
-	fmt.Println(t.header())
-	f := t.fmt + "\n"
+func (t *topic) print(out, errs io.Writer, format string) error {
+	w, err := output.New(out, format, "topic", "partition", "start", "offset", "end", "size")
+	if err != nil {
+		return err
+	}
+
 	for _, p := range t.partitions {
-		fmt.Printf(f, p.Partition, p.Start, p.Offset, p.End, p.End-p.Start)
+		if err := w.Write(t.topic, p.Partition, p.Start, p.Offset, p.End, p.End-p.Start); err != nil {
+			return err
+		}
 	}
+	return w.Close()
 }
 
 type partition struct {
-	cli          *kafka.Client
+	cli          kafka.Reader
 	height       int
 	width        int
 	partition    kafka.Partition
 	rows         []kafka.Message
 	enteredAt    int
 	fmt          string
-	pg           int
+	col          int
 	flashMessage chan<- string
 }
 
-func newPartition(cli *kafka.Client, p kafka.Partition, width, height int, flashMessage chan<- string) (feeder, error) {
+func newPartition(cli kafka.Reader, p kafka.Partition, width, height int, flashMessage chan<- string) (feeder, error) {
 	rows, err := cli.GetPartition(p, height, func(_ []byte) bool { return true })
 	return &partition{
 		cli:          cli,
@@ -256,59 +572,227 @@ func (p *partition) jump(i int64) error {
 		return nil
 	}
 
-	p.pg = int(i) / p.height
-	p.partition.Offset = i
-	rows, err := p.cli.GetPartition(p.partition, p.height, func(_ []byte) bool { return true })
+	if i < p.partition.Start {
+		i = p.partition.Start
+	}
+
+	return p.fetch(i)
+}
+
+//fetch gets a page of messages starting at offset o (or the
+//first message after it if o has been compacted away).
+func (p *partition) fetch(o int64) error {
+	part := p.partition
+	part.Offset = o
+	rows, err := p.cli.GetPartition(part, p.height, func(_ []byte) bool { return true })
 	if err != nil {
 		return err
 	}
+
+	p.partition = part
 	p.rows = rows
 	return nil
 }
 
 func (p *partition) row() int { return p.enteredAt }
 
+//current is the message at row (nil if there isn't one).
+func (p *partition) current(row int) *kafka.Message {
+	if row < 0 || row >= len(p.rows) {
+		return nil
+	}
+	return &p.rows[row]
+}
+
+//resize fetches a page that fits the new height.  It starts
+//with the same message as before unless the one at the cursor
+//would fall off the bottom, in which case that one goes first.
+func (p *partition) resize(width, height, row int) (int, error) {
+	p.width = width
+	if height == p.height {
+		return row, nil
+	}
+	p.height = height
+
+	o := p.partition.Offset
+	if len(p.rows) > 0 {
+		o = p.rows[0].Offset
+	}
+
+	if row >= height && row < len(p.rows) {
+		o = p.rows[row].Offset
+		row = 0
+	}
+
+	p.enteredAt = row
+	return row, p.fetch(o)
+}
+
 func (p *partition) header() string {
-	return fmt.Sprintf(
-		"offset       message    topic: %s partition: %d start: %d end: %d",
+	cols := "message   "
+	if kw := p.keyWidth(); kw > 0 {
+		cols = fmt.Sprintf("%-*s %s", kw, "key", cols)
+	}
+
+	h := fmt.Sprintf(
+		"offset       %s topic: %s partition: %d start: %d end: %d",
+		cols,
 		p.partition.Topic,
 		p.partition.Partition,
 		p.partition.Start,
 		p.partition.End,
 	)
+
+	var n int
+	for _, msg := range p.rows {
+		if len(decodeErrors(msg)) > 0 {
+			n++
+		}
+	}
+
+	if n > 0 {
+		h = fmt.Sprintf("%s decode errors: %d", h, n)
+	}
+
+	if p.col > 0 {
+		h = fmt.Sprintf("%s (column %d)", h, p.col)
+	}
+	return h
 }
 
 func (p *partition) getRows() ([]string, error) {
+	kw := p.keyWidth()
+	width := p.width - 13
+	if kw > 0 {
+		width -= kw + 1
+	}
+
 	out := make([]string, len(p.rows))
 	for i, msg := range p.rows {
-		end := p.width
-		if len(msg.Value) < end {
-			end = len(msg.Value)
+		val := string(msg.Value)
+		if msg.DecodeError != "" {
+			val = fmt.Sprintf("decode error (%s): %s", msg.DecodeError, val)
 		}
-		out[i] = fmt.Sprintf(p.fmt, p.partition.Offset+int64(i), string(msg.Value[:end]))
+		val = clip(val, p.col, width)
+		if kw > 0 {
+			val = fmt.Sprintf("%s %s", pad(clip(string(msg.Key), 0, kw), kw), val)
+		}
+		out[i] = fmt.Sprintf(p.fmt, msg.Offset, val)
 	}
 
 	return out, nil
 }
 
+//scroll moves the messages n columns to the right (or left
+//if n is negative).
+func (p *partition) scroll(n int) {
+	p.col += n
+	if p.col < 0 {
+		p.col = 0
+	}
+}
+
+//keyWidth is the width of the key column, which is only shown
+//when some message on the page has a key.
+func (p *partition) keyWidth() int {
+	var w int
+	for _, msg := range p.rows {
+		if kw := textWidth(string(msg.Key)); kw > w {
+			w = kw
+		}
+	}
+
+	if w > p.width/4 {
+		w = p.width / 4
+	}
+	return w
+}
+
+//page moves pg pages forward or back.  Offsets aren't always
+//contiguous (log compaction and transaction markers leave
+//gaps) so pages are worked out from the offsets of the
+//messages that are on the screen rather than by counting.
 func (p *partition) page(pg int) error {
-	if p.pg == 0 && pg < 0 && p.partition.Offset == p.partition.Start {
+	for ; pg > 0; pg-- {
+		if err := p.forward(); err != nil {
+			return err
+		}
+	}
+
+	for ; pg < 0; pg++ {
+		if err := p.back(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//forward starts the next page right after the last message
+//on this one.
+func (p *partition) forward() error {
+	if len(p.rows) == 0 {
 		return nil
-	} else if p.pg == 0 && pg < 0 && p.partition.Offset > p.partition.Start {
-		pg = 0
 	}
 
-	o := int64((p.pg+pg)*p.height) + p.partition.Start
+	o := p.rows[len(p.rows)-1].Offset + 1
 	if o >= p.partition.End {
 		return nil
 	}
-	p.pg += pg
-	p.partition.Offset = o
-	rows, err := p.cli.GetPartition(p.partition, p.height, func(_ []byte) bool { return true })
-	if err != nil {
-		return err
+	return p.fetch(o)
+}
+
+//back gets the page of messages before the first one on this
+//page.  Since there's no telling how many offsets are missing
+//it reads a bigger and bigger window until it has a full page
+//(or reaches the start of the partition).
+func (p *partition) back() error {
+	first := p.partition.Offset
+	if len(p.rows) > 0 {
+		first = p.rows[0].Offset
 	}
-	p.rows = rows
+
+	if first <= p.partition.Start {
+		return nil
+	}
+
+	var before []kafka.Message
+	for n := int64(p.height); ; n *= 2 {
+		//There are at most first - Offset messages before the
+		//first row, so that many is the most that is fetched
+		//(gaps mean some may come from after it).
+		part := p.partition
+		part.Offset = first - n
+		if part.Offset < p.partition.Start {
+			part.Offset = p.partition.Start
+		}
+
+		rows, err := p.cli.GetPartition(part, int(first-part.Offset), func(_ []byte) bool { return true })
+		if err != nil {
+			return err
+		}
+
+		before = before[:0]
+		for _, msg := range rows {
+			if msg.Offset < first {
+				before = append(before, msg)
+			}
+		}
+
+		if len(before) >= p.height || part.Offset == p.partition.Start {
+			break
+		}
+	}
+
+	if len(before) == 0 {
+		return nil
+	}
+
+	if len(before) > p.height {
+		before = before[len(before)-p.height:]
+	}
+
+	p.rows = before
+	p.partition.Offset = before[0].Offset
 	return nil
 }
 
@@ -321,10 +805,34 @@ func (p *partition) enter(row int) (feeder, error) {
 	return newMessage(p.rows[row], p.width, p.height, p.flashMessage)
 }
 
-func (p *partition) print() {
-	p.cli.Fetch(p.partition, p.partition.End, func(s string) {
-		fmt.Println(s)
+func (p *partition) print(out, errs io.Writer, format string) error {
+	w, err := output.New(out, format, output.MessageColumns...)
+	if err != nil {
+		return err
+	}
+
+	//Fetch can't be stopped, so once a write fails the rest of
+	//the messages are skipped and the first error is returned.
+	var werr error
+	err = p.cli.Fetch(p.partition, p.partition.End, func(msg kafka.Message) {
+		if werr != nil {
+			return
+		}
+
+		for _, e := range decodeErrors(msg) {
+			fmt.Fprintf(errs, "offset %d: %s\n", msg.Offset, e)
+		}
+		werr = w.Message(msg)
 	})
+
+	if err != nil {
+		return err
+	}
+
+	if werr != nil {
+		return werr
+	}
+	return w.Close()
 }
 
 type message struct {
@@ -333,6 +841,10 @@ type message struct {
 	msg          kafka.Message
 	enteredAt    int
 	body         []string
+	pretty       []string
+	hex          bool
+	wrap         bool
+	col          int
 	pg           int
 	offset       int
 	flashMessage chan<- string
@@ -350,22 +862,115 @@ func newMessage(msg kafka.Message, width, height int, flashMessage chan<- string
 		body = append(body, scanner.Text())
 	}
 
-	return &message{
+	m := &message{
 		width:        width,
 		height:       height,
 		msg:          msg,
-		body:         body,
+		pretty:       body,
 		flashMessage: flashMessage,
-	}, nil
+	}
+
+	// binary data scrambles the terminal, so show it as hex
+	m.setHex(!utf8.Valid(msg.Value))
+	return m, nil
+}
+
+func (m *message) toggleHex() {
+	m.setHex(!m.hex)
+}
+
+func (m *message) setHex(h bool) {
+	m.hex = h
+	m.layout()
 }
 
-func (m *message) print() {
-	for _, r := range m.body {
-		fmt.Println(r)
+func (m *message) toggleWrap() {
+	m.wrap = !m.wrap
+	m.layout()
+}
+
+//scroll moves the message n columns to the right (or left if
+//n is negative).  Wrapped messages don't need scrolling.
+func (m *message) scroll(n int) {
+	if m.wrap {
+		return
+	}
+
+	m.col += n
+	if m.col < 0 {
+		m.col = 0
 	}
 }
 
+//layout splits the message up into the lines of the body.
+func (m *message) layout() {
+	m.pg = 0
+	m.offset = 0
+	m.col = 0
+
+	m.body = nil
+	if errs := decodeErrors(m.msg); len(errs) > 0 {
+		for _, e := range errs {
+			m.body = append(m.body, c3(e))
+		}
+		m.body = append(m.body, "")
+	}
+
+	lines := m.pretty
+	if m.hex {
+		lines = hexDump(m.msg.Value)
+	}
+
+	if !m.wrap || m.hex {
+		m.body = append(m.body, lines...)
+		return
+	}
+
+	for _, l := range lines {
+		m.body = append(m.body, wrap(l, m.width)...)
+	}
+}
+
+//decodeErrors are shown above a message that the Decoder
+//couldn't (completely) decode.
+func decodeErrors(msg kafka.Message) []string {
+	var out []string
+	if msg.DecodeError != "" {
+		out = append(out, fmt.Sprintf("decode error: %s", msg.DecodeError))
+	}
+
+	if msg.KeyDecodeError != "" {
+		out = append(out, fmt.Sprintf("key decode error: %s", msg.KeyDecodeError))
+	}
+	return out
+}
+
+func (m *message) print(out, errs io.Writer, format string) error {
+	if format == output.Raw {
+		for _, r := range m.body {
+			if _, err := fmt.Fprintln(out, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w, err := output.New(out, format, output.MessageColumns...)
+	if err != nil {
+		return err
+	}
+
+	if err := w.Message(m.msg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
 func (m *message) search(s string, cb func(int64, int64)) (int64, error) {
+	if m.hex {
+		return m.searchHex(s)
+	}
+
 	for i, r := range m.body {
 		j := strings.Index(r, s)
 		if j > -1 {
@@ -376,6 +981,27 @@ func (m *message) search(s string, cb func(int64, int64)) (int64, error) {
 	return -1, nil
 }
 
+//searchHex looks for a byte pattern (given as hex) in the raw
+//message and returns the offset of the first byte that matched.
+//Anything that isn't hex is searched for as plain text.
+func (m *message) searchHex(s string) (int64, error) {
+	b, err := parseHex(s)
+	if err != nil || len(b) == 0 {
+		b = []byte(s)
+	}
+
+	i := bytes.Index(m.msg.Value, b)
+	if i == -1 {
+		return -1, nil
+	}
+
+	row := i / hexWidth
+	if n := len(decodeErrors(m.msg)); n > 0 {
+		row += n + 1
+	}
+	return int64(i), m.jump(int64(row))
+}
+
 func (m *message) jump(i int64) error {
 	pg := int(i) / m.height
 	o := int(i) % m.height
@@ -390,13 +1016,55 @@ func (m *message) jump(i int64) error {
 
 func (m *message) row() int { return m.enteredAt }
 
+func (m *message) resize(width, height, row int) (int, error) {
+	start := m.pg*m.height + m.offset
+	if row >= height {
+		start += row
+		row = 0
+	}
+
+	m.height = height
+	if m.wrap && width != m.width {
+		//the lines are different now so start at the top
+		m.width = width
+		m.layout()
+		m.enteredAt = 0
+		return 0, nil
+	}
+
+	m.width = width
+	m.pg = start / height
+	m.offset = start % height
+	m.enteredAt = row
+	return row, nil
+}
+
 func (m *message) header() string {
-	return fmt.Sprintf(
+	h := fmt.Sprintf(
 		"topic: %s partition: %d offset: %d",
 		m.msg.Partition.Topic,
 		m.msg.Partition.Partition,
 		m.msg.Offset,
 	)
+
+	if len(m.msg.Key) > 0 {
+		h = fmt.Sprintf("%s key: %s", h, m.msg.Key)
+	}
+
+	if len(m.msg.Encodings) > 0 {
+		h = fmt.Sprintf("%s encoding: %s", h, strings.Join(m.msg.Encodings, " > "))
+	}
+
+	if m.hex {
+		h = fmt.Sprintf("%s (hex)", h)
+	} else if m.wrap {
+		h = fmt.Sprintf("%s (wrapped)", h)
+	}
+
+	if m.col > 0 {
+		h = fmt.Sprintf("%s (column %d)", h, m.col)
+	}
+	return h
 }
 
 func (m *message) page(pg int) error {
@@ -426,7 +1094,12 @@ func (m *message) getRows() ([]string, error) {
 	if end >= len(m.body) {
 		end = len(m.body)
 	}
-	return m.body[start:end], nil
+
+	out := make([]string, end-start)
+	for i, r := range m.body[start:end] {
+		out[i] = clip(r, m.col, m.width)
+	}
+	return out, nil
 }
 
 func prettyMessage(val []byte) (io.Reader, error) {
@@ -25,30 +25,43 @@ type footer struct {
 
 	jump   func(int64) error
 	offset func(int64) error
+	filter func(string) error
 	search chan<- string
+
+	command  func(string) error
+	complete func(string) []string
+	//completions are cycled through by pressing tab again
+	completions []string
+	completion  int
 }
 
-func newFooter(g *ui.Gui, w, h int, ch <-chan string, jump func(int64) error, offset func(int64) error, search chan<- string) *footer {
+func newFooter(g *ui.Gui, w, h int, ch <-chan string, jump func(int64) error, offset func(int64) error, filter func(string) error, search chan<- string) *footer {
 	f := &footer{
 		name:   "footer",
 		coords: coords{x1: -1, y1: h - 2, x2: w, y2: h},
 		width:  w,
 		jump:   jump,
 		offset: offset,
+		filter: filter,
 		search: search,
 	}
 	go f.flashMessage(g, ch)
 	return f
 }
 
+func (f *footer) resize(w, h int) {
+	f.width = w
+	f.coords = coords{x1: -1, y1: h - 2, x2: w, y2: h}
+}
+
 func (f *footer) Edit(v *ui.View, key ui.Key, ch rune, mod ui.Modifier) {
 	in := string(ch)
 	if key == ui.KeySpace {
 		in = " "
 	}
+	f.completions = nil
 	s := strings.TrimSpace(v.Buffer())
-	if key == 127 && len(s) > 0+len(f.function)+2 {
-		log.Println("key", key, s, len(s) > 0+len(f.function)+2)
+	if key == 127 && len(s) > len(f.prompt()) {
 		v.Clear()
 		s = s[:len(s)-1]
 		v.Write([]byte(c1(s)))
@@ -57,7 +70,26 @@ func (f *footer) Edit(v *ui.View, key ui.Key, ch rune, mod ui.Modifier) {
 		fmt.Fprint(v, c1(in))
 		s = v.Buffer()
 		v.SetCursor(len(s)-1, 0)
+	} else {
+		return
+	}
+
+	// the topic list is filtered as you type
+	if f.function == "filter" {
+		if err := f.filter(f.term(v)); err != nil {
+			log.Println("could not filter topics", err)
+		}
+	}
+}
+
+//term is what has been typed after the function's prompt.
+func (f *footer) term(v *ui.View) string {
+	s := v.Buffer()
+	i := strings.Index(s, ":")
+	if i == -1 {
+		return ""
 	}
+	return strings.TrimSpace(s[i+1:])
 }
 
 func (f *footer) enter(g *ui.Gui, function string) error {
@@ -66,10 +98,61 @@ func (f *footer) enter(g *ui.Gui, function string) error {
 		return err
 	}
 
-	v.Clear()
-	v.Write([]byte(c1(fmt.Sprintf("%s: ", function))))
 	f.function = function
-	return v.SetCursor(len(function)+2, 0)
+	f.completions = nil
+	v.Clear()
+	v.Write([]byte(c1(f.prompt())))
+	return v.SetCursor(len(f.prompt()), 0)
+}
+
+//prompt is what the footer starts with while typing in it.
+//Commands get a vi style ':'.
+func (f *footer) prompt() string {
+	if f.function == "command" {
+		return ":"
+	}
+	return fmt.Sprintf("%s: ", f.function)
+}
+
+//tab completes the command line.  The first tab fills in as
+//much as all of the possible completions have in common and
+//each tab after that moves on to the next one.
+func (f *footer) tab(g *ui.Gui, v *ui.View) error {
+	if f.function != "command" {
+		return nil
+	}
+
+	line := strings.TrimPrefix(strings.TrimRight(v.Buffer(), "\n"), f.prompt())
+	if f.completions == nil {
+		f.completions = f.complete(line)
+		f.completion = -1
+		if len(f.completions) == 0 {
+			f.completions = nil
+			return nil
+		}
+
+		line = commonPrefix(f.completions)
+		if len(f.completions) == 1 {
+			f.completions = nil
+		}
+	} else {
+		f.completion = (f.completion + 1) % len(f.completions)
+		line = f.completions[f.completion]
+	}
+
+	v.Clear()
+	v.Write([]byte(c1(f.prompt() + line)))
+	return v.SetCursor(len(f.prompt())+len(line), 0)
+}
+
+func commonPrefix(s []string) string {
+	p := s[0]
+	for _, x := range s[1:] {
+		for !strings.HasPrefix(x, p) {
+			p = p[:len(p)-1]
+		}
+	}
+	return p
 }
 
 func (f *footer) bail(g *ui.Gui, v *ui.View) error {
@@ -78,13 +161,21 @@ func (f *footer) bail(g *ui.Gui, v *ui.View) error {
 	return nil
 }
 
+//cancel is bail for the esc key, which also undoes a filter.
+func (f *footer) cancel(g *ui.Gui, v *ui.View) error {
+	if f.function == "filter" {
+		if err := f.filter(""); err != nil {
+			return err
+		}
+	}
+	return f.bail(g, v)
+}
+
 func (f *footer) exit(g *ui.Gui, v *ui.View) error {
-	s := v.Buffer()
-	i := strings.Index(s, ":")
-	if i == -1 {
+	if !strings.Contains(v.Buffer(), ":") {
 		return nil
 	}
-	term := strings.TrimSpace(s[i+1:])
+	term := f.term(v)
 	switch f.function {
 	case "jump":
 		n, err := strconv.ParseInt(strings.TrimSpace(term), 10, 64)
@@ -106,6 +197,11 @@ func (f *footer) exit(g *ui.Gui, v *ui.View) error {
 		if err := f.offset(n); err != nil {
 			return err
 		}
+	case "command":
+		if err := f.bail(g, v); err != nil {
+			return err
+		}
+		return f.command(term)
 	}
 
 	return f.bail(g, v)
@@ -117,6 +213,8 @@ func (f *footer) acceptable(s string) bool {
 		return f.isChar(s)
 	case "filter":
 		return f.isChar(s)
+	case "command":
+		return f.isChar(s)
 	case "jump":
 		return f.isNum(s)
 	case "offset":
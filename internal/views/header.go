@@ -8,6 +8,7 @@ import (
 
 type header struct {
 	text   string
+	tabs   string
 	name   string
 	coords coords
 	width  int
@@ -29,7 +30,13 @@ func (h *header) resize(w, _ int) {
 
 func (h *header) Render(g *ui.Gui, v *ui.View) error {
 	v.Clear()
-	t := fmt.Sprintf("%%s%%%ds", h.width-len(h.text))
-	_, err := v.Write([]byte(c1(fmt.Sprintf(t, h.text, "type 'h' for help"))))
+	help := h.tabs + "type 'h' for help"
+	text := h.text
+	if len(text)+len(help) > h.width {
+		// keep the tabs on the screen
+		text = clip(text, 0, h.width-len(help)-1)
+	}
+	t := fmt.Sprintf("%%s%%%ds", h.width-len(text))
+	_, err := v.Write([]byte(c1(fmt.Sprintf(t, text, help))))
 	return err
 }
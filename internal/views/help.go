@@ -8,9 +8,8 @@ import (
 )
 
 var (
-	helpWidth  = 49
-	helpHeight = 15
-	tpl        = `%s             C-x means Control x`
+	helpWidth = 49
+	tpl       = `%s             C-x means Control x`
 )
 
 type help struct {
@@ -25,8 +24,13 @@ func newHelp(w, h int) *help {
 	}
 }
 
-func getHelpCoords(g *ui.Gui) coords {
+//getHelpCoords centers the help box, cutting it down to fit
+//short screens (it scrolls with n and p).
+func getHelpCoords(g *ui.Gui, helpHeight int) coords {
 	maxX, maxY := g.Size()
+	if helpHeight > maxY-2 {
+		helpHeight = maxY - 2
+	}
 	x1 := maxX/2 - helpWidth/2
 	x2 := maxX/2 + helpWidth/2
 	y1 := maxY/2 - helpHeight/2
@@ -34,40 +38,94 @@ func getHelpCoords(g *ui.Gui) coords {
 	return coords{x1: x1, y1: y1, x2: x2, y2: y2}
 }
 
-func (h *help) show(g *ui.Gui, v *ui.View, keys []key) error {
+func (h *help) show(g *ui.Gui, v *ui.View, keys []key, commands []command) error {
 	v.Editable = false
 	if h.body == nil {
-		h.body = h.getBody(keys)
+		h.body = h.getBody(keys, commands)
 	}
 
 	var err error
 
-	coords := getHelpCoords(g)
+	coords := getHelpCoords(g, bytes.Count(h.body, []byte("\n"))+2)
 
 	v, err = g.SetView("help", coords.x1, coords.y1, coords.x2, coords.y2)
 	if err != ui.ErrUnknownView {
 		return err
 	}
 
-	v.Title = h.name
-
+	h.setTitle(v)
 	v.Write([]byte(h.body))
 	_, err = g.SetCurrentView("help")
 	return err
 }
 
+//setTitle tells the user the help can be scrolled when it
+//doesn't fit.
+func (h *help) setTitle(v *ui.View) {
+	v.Title = h.name
+	if _, height := v.Size(); height < h.lines() {
+		v.Title = h.name + " (n/p to scroll)"
+	}
+}
+
+func (h *help) lines() int {
+	return bytes.Count(h.body, []byte("\n")) + 1
+}
+
+//scroll moves the help text by dy lines when it doesn't fit
+//on the screen.
+func (h *help) scroll(dy int) func(*ui.Gui, *ui.View) error {
+	return func(g *ui.Gui, v *ui.View) error {
+		_, y := v.Origin()
+		_, height := v.Size()
+		y += dy
+		if y > h.lines()-height {
+			y = h.lines() - height
+		}
+		if y < 0 {
+			y = 0
+		}
+		return v.SetOrigin(0, y)
+	}
+}
+
+//resize keeps the help box (if it is open) in the middle of
+//the screen.
+func (h *help) resize(g *ui.Gui) error {
+	if _, err := g.View(h.name); err != nil {
+		return nil
+	}
+
+	coords := getHelpCoords(g, bytes.Count(h.body, []byte("\n"))+2)
+	v, err := g.SetView(h.name, coords.x1, coords.y1, coords.x2, coords.y2)
+	if err != nil {
+		return err
+	}
+
+	h.setTitle(v)
+	return h.scroll(0)(g, v)
+}
+
 func (h *help) hide(g *ui.Gui, v *ui.View) error {
 	v.Clear()
 	return g.DeleteView(h.name)
 }
 
-func (h *help) getBody(keys []key) []byte {
+func (h *help) getBody(keys []key, commands []command) []byte {
 	out := &bytes.Buffer{}
 	for _, key := range keys {
-		h := key.help
-		if h.key != "" {
-			fmt.Fprintf(out, fmt.Sprintf("%s %s\n", c3(h.key), c1(fmt.Sprintf(fmt.Sprintf("%%%ds", helpWidth-len(h.key)-4), h.body))))
-		}
+		writeHelp(out, key.help)
+	}
+
+	fmt.Fprintln(out, "")
+	for _, cmd := range commands {
+		writeHelp(out, cmd.help)
 	}
 	return []byte(fmt.Sprintf(tpl, out.String()))
 }
+
+func writeHelp(out *bytes.Buffer, h keyHelp) {
+	if h.key != "" {
+		fmt.Fprintf(out, fmt.Sprintf("%s %s\n", c3(h.key), c1(fmt.Sprintf(fmt.Sprintf("%%%ds", helpWidth-len(h.key)-4), h.body))))
+	}
+}
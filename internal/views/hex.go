@@ -0,0 +1,55 @@
+package views
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const hexWidth = 16
+
+//hexDump formats val the same way that xxd does: an offset,
+//sixteen bytes as hex in groups of two, then the same bytes as
+//ascii with anything unprintable shown as a '.'.
+func hexDump(val []byte) []string {
+	var out []string
+	for i := 0; i < len(val); i += hexWidth {
+		end := i + hexWidth
+		if end > len(val) {
+			end = len(val)
+		}
+
+		line := val[i:end]
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "%08x: ", i)
+		for j := 0; j < hexWidth; j++ {
+			if j < len(line) {
+				fmt.Fprintf(&buf, "%02x", line[j])
+			} else {
+				buf.WriteString("  ")
+			}
+			if j%2 == 1 {
+				buf.WriteByte(' ')
+			}
+		}
+
+		buf.WriteByte(' ')
+		for _, b := range line {
+			if b < 32 || b > 126 {
+				b = '.'
+			}
+			buf.WriteByte(b)
+		}
+		out = append(out, buf.String())
+	}
+	return out
+}
+
+//parseHex turns a search term like "dead beef" or "0xdeadbeef"
+//into the bytes it represents.
+func parseHex(s string) ([]byte, error) {
+	s = strings.Replace(s, " ", "", -1)
+	s = strings.TrimPrefix(s, "0x")
+	return hex.DecodeString(s)
+}
@@ -0,0 +1,90 @@
+package views
+
+import (
+	"log"
+	"sync"
+
+	"github.com/cswank/kcli/pkg/kafka"
+)
+
+const (
+	//infoWorkers is how many topics are summed up at once
+	infoWorkers = 8
+)
+
+//topicInfo loads the partition count, message total and
+//replication factor of topics in the background so that the
+//topic list doesn't have to wait for them.  update is called
+//each time one arrives so the screen gets redrawn.
+type topicInfo struct {
+	cli    kafka.Reader
+	update func()
+	lock   sync.Mutex
+	info   map[string]*infoResult
+	sem    chan struct{}
+}
+
+type infoResult struct {
+	done bool
+	info kafka.TopicInfo
+	err  error
+}
+
+func newTopicInfo(cli kafka.Reader, update func()) *topicInfo {
+	return &topicInfo{
+		cli:    cli,
+		update: update,
+		info:   map[string]*infoResult{},
+		sem:    make(chan struct{}, infoWorkers),
+	}
+}
+
+//get returns what is known about topic so far and starts
+//loading it if nobody has asked for it before.
+func (t *topicInfo) get(topic string) infoResult {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	r, ok := t.info[topic]
+	if !ok {
+		r = &infoResult{}
+		t.info[topic] = r
+		go t.fetch(topic, r)
+	}
+	return *r
+}
+
+//wait is get for when there's no screen to redraw (printing).
+func (t *topicInfo) wait(topic string) (kafka.TopicInfo, error) {
+	t.lock.Lock()
+	var r infoResult
+	if p, ok := t.info[topic]; ok {
+		r = *p
+	}
+	t.lock.Unlock()
+
+	if r.done {
+		return r.info, r.err
+	}
+	return t.cli.GetTopicInfo(topic)
+}
+
+func (t *topicInfo) fetch(topic string, r *infoResult) {
+	t.sem <- struct{}{}
+	info, err := t.cli.GetTopicInfo(topic)
+	<-t.sem
+
+	if err != nil {
+		log.Printf("could not get info for topic %s: %s", topic, err)
+	}
+
+	t.lock.Lock()
+	r.done = true
+	r.info = info
+	r.err = err
+	t.lock.Unlock()
+
+	if t.update != nil {
+		t.update()
+	}
+}
@@ -28,14 +28,44 @@ func (s *screen) getKeys() []key {
 		{views: []string{s.body.name}, keys: []binding{'b', ui.KeyArrowLeft}, keybinding: s.locked(s.body.back), help: keyHelp{key: "b", body: "(or left arrow) backward to prev page"}},
 		{views: []string{s.body.name}, keys: []binding{ui.KeyEnter}, keybinding: s.locked(s.enter), help: keyHelp{key: "enter", body: "view item at cursor"}},
 		{views: []string{s.body.name}, keys: []binding{ui.KeyEsc}, keybinding: s.locked(s.escape), help: keyHelp{key: "esc", body: "back to previous view"}},
-		{views: []string{s.body.name}, keys: []binding{ui.KeyCtrlJ}, keybinding: s.locked(s.jump), help: keyHelp{key: "C-j", body: "jump to a kafka offset"}},
+		{views: []string{s.body.name}, keys: []binding{ui.KeyCtrlJ}, keybinding: s.locked(s.jump), help: keyHelp{key: "C-j", body: "jump to a kafka offset (or partition)"}},
 		{views: []string{s.body.name}, keys: []binding{ui.KeyCtrlO}, keybinding: s.locked(s.offset), help: keyHelp{key: "C-o", body: "set the offset in all partitions of topic"}},
 		{views: []string{s.body.name}, keys: []binding{ui.KeyCtrlS, '/'}, keybinding: s.locked(s.search), help: keyHelp{key: "C-s", body: "(or /) search kafka messages"}},
+		{views: []string{s.body.name}, keys: []binding{ui.KeyCtrlF}, keybinding: s.locked(s.filter), help: keyHelp{key: "C-f", body: "filter topics as you type (esc clears)"}},
+		{views: []string{s.body.name}, keys: []binding{'i'}, keybinding: s.locked(s.info), help: keyHelp{key: "i", body: "show partition, message and replica counts"}},
+		{views: []string{s.body.name}, keys: []binding{'t'}, keybinding: s.locked(s.tree), help: keyHelp{key: "t", body: "toggle a tree of topics grouped by name"}},
+		{views: []string{s.body.name}, keys: []binding{'_'}, keybinding: s.locked(s.internal), help: keyHelp{key: "_", body: "hide (or show) internal topics"}},
+		{views: []string{s.body.name}, keys: []binding{'s'}, keybinding: s.locked(s.sort), help: keyHelp{key: "s", body: "sort partitions by the next column"}},
+		{views: []string{s.body.name}, keys: []binding{'r'}, keybinding: s.locked(s.reverse), help: keyHelp{key: "r", body: "reverse the sort order of partitions"}},
+		{views: []string{s.body.name}, keys: []binding{'v'}, keybinding: s.locked(s.togglePreview), help: keyHelp{key: "v", body: "toggle a preview of the selected message"}},
+		{views: []string{s.body.name}, keys: []binding{'>'}, keybinding: s.locked(s.right), help: keyHelp{key: ">", body: "scroll messages to the right"}},
+		{views: []string{s.body.name}, keys: []binding{'<'}, keybinding: s.locked(s.left), help: keyHelp{key: "<", body: "scroll messages to the left"}},
+		{views: []string{s.body.name}, keys: []binding{'w'}, keybinding: s.locked(s.wrap), help: keyHelp{key: "w", body: "toggle wrapping of long lines in a message"}},
+		{views: []string{s.body.name}, keys: []binding{'x'}, keybinding: s.locked(s.hex), help: keyHelp{key: "x", body: "toggle hex view of a message"}},
+		{views: []string{s.body.name}, keys: []binding{':'}, keybinding: s.locked(s.commandLine), help: keyHelp{key: ":", body: "run one of the commands below"}},
+		{views: []string{s.body.name}, keys: []binding{ui.KeyCtrlT}, keybinding: s.locked(s.newTab), help: keyHelp{key: "C-t", body: "open a new tab with the list of topics"}},
+		{views: []string{s.body.name}, keys: []binding{ui.KeyTab}, keybinding: s.locked(s.nextTab), help: keyHelp{key: "tab", body: "switch to the next tab"}},
+		{views: []string{s.body.name}, keys: []binding{ui.KeyCtrlW}, keybinding: s.locked(s.closeTab), help: keyHelp{key: "C-w", body: "close the current tab"}},
 		{views: []string{s.body.name}, keys: []binding{ui.KeyCtrlP}, keybinding: s.locked(s.dump), help: keyHelp{key: "C-p", body: "quit and print to stdout"}},
 		{views: []string{s.body.name}, keys: []binding{ui.KeyCtrlD, ui.KeyCtrlC}, keybinding: s.quit, help: keyHelp{key: "C-d (or C-c)", body: "quit"}},
 		{views: []string{s.footer.name}, keys: []binding{ui.KeyEnter}, keybinding: s.footer.exit},
-		{views: []string{s.footer.name}, keys: []binding{ui.KeyEsc}, keybinding: s.footer.bail},
+		{views: []string{s.footer.name}, keys: []binding{ui.KeyEsc}, keybinding: s.footer.cancel},
+		{views: []string{s.footer.name}, keys: []binding{ui.KeyTab}, keybinding: s.footer.tab},
 		{views: []string{s.body.name}, keys: []binding{'h'}, keybinding: s.showHelp, help: keyHelp{key: "h", body: "toggle help"}},
 		{views: []string{s.help.name}, keys: []binding{'h'}, keybinding: s.hideHelp},
+		{views: []string{s.help.name}, keys: []binding{'n', ui.KeyArrowDown}, keybinding: s.help.scroll(1)},
+		{views: []string{s.help.name}, keys: []binding{'p', ui.KeyArrowUp}, keybinding: s.help.scroll(-1)},
+	}
+}
+
+//getCommands are the commands that can be typed after ':'.
+func (s *screen) getCommands() []command {
+	return []command{
+		{name: "topic", run: s.topicCommand, complete: s.topicNames, help: keyHelp{key: ":topic NAME", body: "go to a topic"}},
+		{name: "jump", run: s.jumpCommand, help: keyHelp{key: ":jump N", body: "jump to an offset (like C-j)"}},
+		{name: "export", run: s.exportCommand, help: keyHelp{key: ":export FILE", body: "write the view to a file"}},
+		{name: "export!", run: s.overwriteCommand, help: keyHelp{key: ":export! FILE", body: "overwrite FILE with the view"}},
+		{name: "decoder", run: s.decoderCommand, help: keyHelp{key: ":decoder NAME", body: "decode messages with NAME"}},
+		{name: "set", run: s.setCommand, complete: s.settingNames, help: keyHelp{key: ":set OPTION", body: "wrap, nowrap, preview or nopreview"}},
 	}
 }
@@ -0,0 +1,106 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/cswank/kcli/pkg/kafka"
+	ui "github.com/jroimartin/gocui"
+)
+
+const (
+	//previewSide is how wide the screen has to be for the
+	//preview to go beside the list of messages instead of
+	//under it
+	previewSide = 100
+)
+
+//preview shows the message under the cursor next to the list
+//of messages in a partition so that they can be scanned
+//without entering each one.
+type preview struct {
+	name   string
+	coords coords
+	on     bool
+	msg    *kafka.Message
+	width  int
+	height int
+	rows   []string
+}
+
+func newPreview() *preview {
+	return &preview{name: "preview"}
+}
+
+func (p *preview) toggle() {
+	p.on = !p.on
+}
+
+//getPreviewCoords is where the preview goes (see
+//getBodyCoords).
+func getPreviewCoords(w, h int) coords {
+	if w >= previewSide {
+		return coords{x1: w / 2, y1: 1, x2: w - 1, y2: h - 2}
+	}
+	return coords{x1: 0, y1: h / 2, x2: w - 1, y2: h - 2}
+}
+
+func (p *preview) resize(w, h int) {
+	p.coords = getPreviewCoords(w, h)
+}
+
+//show lays out msg to fit in the preview.  It is called on
+//every redraw so the work is only done when the cursor has
+//moved to another message (or the preview changed size).
+func (p *preview) show(msg *kafka.Message) error {
+	width := p.coords.x2 - p.coords.x1 - 1
+	height := p.coords.y2 - p.coords.y1 - 1
+	if p.same(msg) && width == p.width && height == p.height {
+		return nil
+	}
+
+	p.msg = msg
+	p.width = width
+	p.height = height
+	p.rows = nil
+	if msg == nil || width < 1 || height < 1 {
+		return nil
+	}
+
+	f, err := newMessage(*msg, width, height, nil)
+	if err != nil {
+		return err
+	}
+
+	m := f.(*message)
+	if !m.hex {
+		m.toggleWrap()
+	}
+
+	p.rows, err = m.getRows()
+	return err
+}
+
+func (p *preview) same(msg *kafka.Message) bool {
+	if p.msg == nil || msg == nil {
+		return p.msg == msg
+	}
+
+	return p.msg.Partition.Topic == msg.Partition.Topic &&
+		p.msg.Partition.Partition == msg.Partition.Partition &&
+		p.msg.Offset == msg.Offset
+}
+
+func (p *preview) Render(g *ui.Gui, v *ui.View) error {
+	v.Clear()
+	v.Title = "preview"
+	if p.msg != nil {
+		v.Title = fmt.Sprintf("offset %d", p.msg.Offset)
+	}
+
+	for _, r := range p.rows {
+		if _, err := fmt.Fprintln(v, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
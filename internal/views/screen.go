@@ -6,7 +6,7 @@ import (
 	"strings"
 
 	"github.com/cswank/kcli/internal/colors"
-	"github.com/cswank/kcli/internal/kafka"
+	"github.com/cswank/kcli/pkg/kafka"
 	ui "github.com/jroimartin/gocui"
 )
 
@@ -27,30 +27,35 @@ type coords struct {
 }
 
 type screen struct {
-	client *kafka.Client
+	client kafka.Reader
 	g      *ui.Gui
 	view   string
 	height int
 	width  int
 	lock   bool
 
-	header *header
-	body   *body
-	footer *footer
-	help   *help
+	header  *header
+	body    *body
+	footer  *footer
+	help    *help
+	preview *preview
 
-	keys []key
+	keys     []key
+	commands []command
 
 	searchChan   <-chan string
 	flashMessage chan<- string
 
-	after func()
+	output      string
+	after       func() error
+	loadDecoder func(string) (kafka.Decoder, error)
 }
 
-func newScreen(cli *kafka.Client, g *ui.Gui, width, height int, opts ...func(*stack) error) (*screen, error) {
+func newScreen(cli kafka.Reader, g *ui.Gui, width, height int, messages <-chan string, opts ...func(*stack) error) (*screen, error) {
 	ch := make(chan string)
 	searchCh := make(chan string)
-	b, err := newBody(cli, width, height, ch, opts...)
+	update := func() { g.Update(func(*ui.Gui) error { return nil }) }
+	b, err := newBody(cli, width, height, ch, update, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -63,15 +68,20 @@ func newScreen(cli *kafka.Client, g *ui.Gui, width, height int, opts ...func(*st
 		height:       height,
 		header:       newHeader(width, height),
 		body:         b,
-		footer:       newFooter(g, width, height, ch, b.jump, b.offset, searchCh),
+		footer:       newFooter(g, width, height, ch, b.jump, b.offset, b.filter, searchCh),
 		help:         newHelp(width, height),
+		preview:      newPreview(),
 		searchChan:   searchCh,
 		flashMessage: ch,
 	}
 
 	go s.doSearch()
+	go s.relay(messages)
 	s.footer.setView = func(v string) { s.view = v }
 	s.keys = s.getKeys()
+	s.commands = s.getCommands()
+	s.footer.command = s.runCommand
+	s.footer.complete = s.complete
 	return s, nil
 }
 
@@ -79,16 +89,32 @@ func (s *screen) getLayout(g *ui.Gui, width, height int) func(*ui.Gui) error {
 	ui.DefaultEditor = s.footer
 
 	return func(g *ui.Gui) error {
+		if w, h := g.Size(); w != s.width || h != s.height {
+			if err := s.resize(g, w, h); err != nil {
+				s.flashMessage <- fmt.Sprintf("could not resize: %s", err)
+			}
+		}
+
 		v, err := g.SetView(s.header.name, s.header.coords.x1, s.header.coords.y1, s.header.coords.x2, s.header.coords.y2)
 		if err != nil && err != ui.ErrUnknownView {
 			return err
 		}
 
 		v.Frame = false
+		s.header.text = s.body.stack.top.header()
+		s.header.tabs = s.body.tabBar()
 		if err := s.header.Render(g, v); err != nil {
 			return err
 		}
 
+		p, split := s.body.stack.top.(*partition)
+		split = split && s.preview.on
+		if c := getBodyCoords(s.width, s.height, split); c != s.body.coords {
+			if err := s.body.resize(c); err != nil {
+				s.flashMessage <- fmt.Sprintf("could not resize: %s", err)
+			}
+		}
+
 		v, err = g.SetView(s.body.name, s.body.coords.x1, s.body.coords.y1, s.body.coords.x2, s.body.coords.y2)
 		if err != nil && err != ui.ErrUnknownView {
 			return err
@@ -100,6 +126,10 @@ func (s *screen) getLayout(g *ui.Gui, width, height int) func(*ui.Gui) error {
 			return err
 		}
 
+		if err := s.renderPreview(g, p, split); err != nil {
+			return err
+		}
+
 		v, err = g.SetView(s.footer.name, s.footer.coords.x1, s.footer.coords.y1, s.footer.coords.x2, s.footer.coords.y2)
 		if err != nil && err != ui.ErrUnknownView {
 			return err
@@ -112,6 +142,41 @@ func (s *screen) getLayout(g *ui.Gui, width, height int) func(*ui.Gui) error {
 	}
 }
 
+//renderPreview shows the message under the cursor of
+//partition p when split is true and removes the preview
+//otherwise.
+func (s *screen) renderPreview(g *ui.Gui, p *partition, split bool) error {
+	if !split {
+		if err := g.DeleteView(s.preview.name); err != nil && err != ui.ErrUnknownView {
+			return err
+		}
+		return nil
+	}
+
+	s.preview.resize(s.width, s.height)
+	c := s.preview.coords
+	v, err := g.SetView(s.preview.name, c.x1, c.y1, c.x2, c.y2)
+	if err != nil && err != ui.ErrUnknownView {
+		return err
+	}
+
+	_, cur := s.body.view.Cursor()
+	if err := s.preview.show(p.current(cur)); err != nil {
+		return err
+	}
+	return s.preview.Render(g, v)
+}
+
+//resize reflows the views after the terminal has changed
+//size.
+func (s *screen) resize(g *ui.Gui, w, h int) error {
+	s.width = w
+	s.height = h
+	s.header.resize(w, h)
+	s.footer.resize(w, h)
+	return s.help.resize(g)
+}
+
 func getColors() (ui.Attribute, colors.Colorer, colors.Colorer, colors.Colorer) {
 	bg = colors.GetBackground(os.Getenv("KCLI_COLOR0"))
 	c1 := colors.Get(os.Getenv("KCLI_COLOR1"))
@@ -171,6 +236,144 @@ func (s *screen) offset(g *ui.Gui, v *ui.View) error {
 	return nil
 }
 
+func (s *screen) filter(g *ui.Gui, v *ui.View) error {
+	r, ok := s.body.stack.top.(*root)
+	if !ok {
+		s.flashMessage <- "you can only filter the list of topics"
+		return nil
+	}
+	s.view = "footer"
+	s.footer.enter(g, "filter")
+	if r.filter != "" {
+		fv, err := g.View(s.footer.name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(fv, c1(r.filter))
+		return fv.SetCursor(len("filter: ")+len(r.filter), 0)
+	}
+	return nil
+}
+
+func (s *screen) info(g *ui.Gui, v *ui.View) error {
+	r, ok := s.body.stack.top.(*root)
+	if !ok {
+		s.flashMessage <- "you can only show topic info in the list of topics"
+		return nil
+	}
+	r.toggleInfo()
+	return nil
+}
+
+func (s *screen) sort(g *ui.Gui, v *ui.View) error {
+	t, ok := s.body.stack.top.(*topic)
+	if !ok {
+		s.flashMessage <- "you can only sort the partitions of a topic"
+		return nil
+	}
+	t.nextSort()
+	return v.SetCursor(0, 0)
+}
+
+func (s *screen) reverse(g *ui.Gui, v *ui.View) error {
+	t, ok := s.body.stack.top.(*topic)
+	if !ok {
+		s.flashMessage <- "you can only sort the partitions of a topic"
+		return nil
+	}
+	t.reverse()
+	return v.SetCursor(0, 0)
+}
+
+func (s *screen) tree(g *ui.Gui, v *ui.View) error {
+	r, ok := s.body.stack.top.(*root)
+	if !ok {
+		s.flashMessage <- "you can only show the list of topics as a tree"
+		return nil
+	}
+	r.toggleTree()
+	return v.SetCursor(0, 0)
+}
+
+func (s *screen) internal(g *ui.Gui, v *ui.View) error {
+	r, ok := s.body.stack.top.(*root)
+	if !ok {
+		s.flashMessage <- "you can only hide internal topics in the list of topics"
+		return nil
+	}
+	r.toggleInternal()
+	return v.SetCursor(0, 0)
+}
+
+func (s *screen) hex(g *ui.Gui, v *ui.View) error {
+	m, ok := s.body.stack.top.(*message)
+	if !ok {
+		s.flashMessage <- "you can only view a message as hex"
+		return nil
+	}
+	m.toggleHex()
+	return v.SetCursor(0, 0)
+}
+
+func (s *screen) newTab(g *ui.Gui, v *ui.View) error {
+	return s.body.newTab()
+}
+
+func (s *screen) nextTab(g *ui.Gui, v *ui.View) error {
+	return s.body.nextTab()
+}
+
+func (s *screen) closeTab(g *ui.Gui, v *ui.View) error {
+	if err := s.body.closeTab(); err != nil {
+		s.flashMessage <- err.Error()
+	}
+	return nil
+}
+
+func (s *screen) togglePreview(g *ui.Gui, v *ui.View) error {
+	if _, ok := s.body.stack.top.(*partition); !ok {
+		s.flashMessage <- "you can only preview the messages in a partition"
+		return nil
+	}
+	s.preview.toggle()
+	return nil
+}
+
+func (s *screen) commandLine(g *ui.Gui, v *ui.View) error {
+	s.view = "footer"
+	return s.footer.enter(g, "command")
+}
+
+func (s *screen) wrap(g *ui.Gui, v *ui.View) error {
+	m, ok := s.body.stack.top.(*message)
+	if !ok {
+		s.flashMessage <- "you can only wrap a message"
+		return nil
+	}
+	m.toggleWrap()
+	return v.SetCursor(0, 0)
+}
+
+func (s *screen) right(g *ui.Gui, v *ui.View) error {
+	return s.scroll(s.width / 2)
+}
+
+func (s *screen) left(g *ui.Gui, v *ui.View) error {
+	return s.scroll(-s.width / 2)
+}
+
+func (s *screen) scroll(n int) error {
+	switch f := s.body.stack.top.(type) {
+	case *partition:
+		f.scroll(n)
+	case *message:
+		f.scroll(n)
+	default:
+		s.flashMessage <- "you can only scroll messages left and right"
+	}
+	return nil
+}
+
 func (s *screen) search(g *ui.Gui, v *ui.View) error {
 	s.lock = true
 	s.view = "footer"
@@ -179,7 +382,8 @@ func (s *screen) search(g *ui.Gui, v *ui.View) error {
 }
 
 func (s *screen) dump(g *ui.Gui, v *ui.View) error {
-	s.after = s.body.stack.top.print
+	top := s.body.stack.top
+	s.after = func() error { return top.print(os.Stdout, os.Stderr, s.output) }
 	return ui.ErrQuit
 }
 
@@ -218,9 +422,15 @@ func (s *screen) doSearch() {
 	}
 }
 
+func (s *screen) relay(messages <-chan string) {
+	for m := range messages {
+		s.flashMessage <- m
+	}
+}
+
 func (s *screen) showHelp(g *ui.Gui, v *ui.View) error {
 	s.view = "help"
-	return s.help.show(g, v, s.keys)
+	return s.help.show(g, v, s.keys, s.commands)
 }
 
 func (s *screen) hideHelp(g *ui.Gui, v *ui.View) error {
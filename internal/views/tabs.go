@@ -0,0 +1,96 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+)
+
+//tab is a stack of feeders (and what was searched for in it)
+//that can be put aside while another tab is on the screen.
+//The tab that is on the screen lives in the body itself.
+type tab struct {
+	stack     stack
+	searchVal string
+	cursor    int
+}
+
+//newTab opens a tab with the list of topics in it and
+//switches to it.
+func (b *body) newTab() error {
+	r, err := newRoot(b.cli, b.width, b.height, b.flashMessage, b.update)
+	if err != nil {
+		return err
+	}
+
+	cur := b.root()
+	r.separator = cur.separator
+	r.info = cur.info
+
+	s, err := newStack(r)
+	if err != nil {
+		return err
+	}
+
+	b.save()
+	b.tabs = append(b.tabs, tab{stack: s})
+	return b.load(len(b.tabs) - 1)
+}
+
+func (b *body) nextTab() error {
+	b.save()
+	return b.load((b.tab + 1) % len(b.tabs))
+}
+
+//closeTab throws away the current tab and switches to the
+//one before it.  The last tab can't be closed.
+func (b *body) closeTab() error {
+	if len(b.tabs) == 1 {
+		return fmt.Errorf("can't close the only tab")
+	}
+
+	b.tabs = append(b.tabs[:b.tab], b.tabs[b.tab+1:]...)
+	i := b.tab - 1
+	if i < 0 {
+		i = 0
+	}
+	return b.load(i)
+}
+
+//save puts the current tab away.
+func (b *body) save() {
+	var cur int
+	if b.view != nil {
+		_, cur = b.view.Cursor()
+	}
+	b.tabs[b.tab] = tab{stack: b.stack, searchVal: b.searchVal, cursor: cur}
+}
+
+//load puts tab i on the screen.
+func (b *body) load(i int) error {
+	t := b.tabs[i]
+	b.tab = i
+	b.stack = t.stack
+	b.searchVal = t.searchVal
+	if b.view == nil {
+		return nil
+	}
+	return b.view.SetCursor(0, t.cursor)
+}
+
+//tabBar lists the tabs for the header with the current one
+//in brackets.  There's nothing to show if there's only one.
+func (b *body) tabBar() string {
+	if len(b.tabs) == 1 {
+		return ""
+	}
+
+	out := make([]string, len(b.tabs))
+	for i := range b.tabs {
+		if i == b.tab {
+			out[i] = fmt.Sprintf("[%d]", i+1)
+		} else {
+			out[i] = fmt.Sprintf("%d", i+1)
+		}
+	}
+	return fmt.Sprintf("tabs: %s  ", strings.Join(out, " "))
+}
@@ -0,0 +1,154 @@
+package views
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+//cellWidth is how many columns the terminal uses to draw r.
+//It matches termbox, which draws zero width and ambiguous
+//runes in a single column.
+func cellWidth(r rune) int {
+	w := runewidth.RuneWidth(r)
+	if w == 0 || w == 2 && runewidth.IsAmbiguousWidth(r) {
+		return 1
+	}
+	return w
+}
+
+//textWidth is how many columns s takes up on the screen,
+//not counting color escape sequences.
+func textWidth(s string) int {
+	var w int
+	walk(s, func(_ string, cw int) bool {
+		w += cw
+		return true
+	})
+	return w
+}
+
+//pad adds spaces to the end of s (which has come from clip)
+//until it is width columns wide.
+func pad(s string, width int) string {
+	var w int
+	walk(s, func(_ string, cw int) bool {
+		if cw > 0 {
+			w++
+		}
+		return true
+	})
+
+	if w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+//clip returns the part of s that is on the screen when it is
+//scrolled from columns to the right and only width columns
+//fit.  A wide rune that is cut in half is replaced with a
+//space.  Color escape sequences are kept so that the text that
+//is left still has the right colors.
+func clip(s string, from, width int) string {
+	var out strings.Builder
+	var col int
+	walk(s, func(r string, w int) bool {
+		if w == 0 {
+			out.WriteString(r)
+			return true
+		}
+
+		start := col
+		col += w
+		if col <= from {
+			return true
+		}
+
+		if col > from+width {
+			return false
+		}
+
+		if start < from {
+			out.WriteString(strings.Repeat(" ", col-from))
+			return true
+		}
+
+		out.WriteString(cell(r, w))
+		return true
+	})
+	return out.String()
+}
+
+//wrap breaks s up into lines that are at most width columns
+//wide.
+func wrap(s string, width int) []string {
+	if width < 2 {
+		return []string{s}
+	}
+
+	var out []string
+	var line strings.Builder
+	var col int
+	walk(s, func(r string, w int) bool {
+		if col+w > width {
+			out = append(out, line.String())
+			line.Reset()
+			col = 0
+		}
+		line.WriteString(cell(r, w))
+		col += w
+		return true
+	})
+	return append(out, line.String())
+}
+
+//cell is what gets written to gocui for a rune that is w
+//columns wide.  gocui puts every rune in a column of its own but
+//the terminal covers the column after a wide rune, so it gets
+//a space to cover up.
+func cell(r string, w int) string {
+	if w == 2 {
+		return r + " "
+	}
+	return r
+}
+
+//walk calls f with each rune of s (as a string) and the
+//number of columns it takes up.  Color escape sequences come
+//through whole with a width of 0.  It stops if f returns false.
+func walk(s string, f func(string, int) bool) {
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			j := i + 1
+			for j < len(s) && !isFinal(s[j]) {
+				j++
+			}
+			if j < len(s) {
+				j++
+			}
+			if !f(s[i:j], 0) {
+				return
+			}
+			i = j
+			continue
+		}
+
+		r, n := utf8.DecodeRuneInString(s[i:])
+		c := s[i : i+n]
+		if r == utf8.RuneError && n == 1 {
+			c = string(utf8.RuneError)
+		}
+
+		if !f(c, cellWidth(r)) {
+			return
+		}
+		i += n
+	}
+}
+
+//isFinal is true for the byte that ends an escape sequence.
+func isFinal(b byte) bool {
+	return b >= '@' && b <= '~' && b != '['
+}
@@ -0,0 +1,89 @@
+package views
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//rootItem is a row in the list of topics.  When the topics
+//are shown as a tree a row is either a topic or a group of
+//topics that share a name prefix.
+type rootItem struct {
+	label string
+	//topic is empty for a group
+	topic string
+	//group is the prefix (including the separator) that
+	//every topic in a group starts with
+	group string
+	//parent is the group this row is in
+	parent string
+	//topics are all of the topics in (or under) the row
+	topics []string
+}
+
+func (i rootItem) isGroup() bool { return i.topic == "" }
+
+func listItems(topics []string) []rootItem {
+	out := make([]rootItem, len(topics))
+	for i, t := range topics {
+		out[i] = rootItem{label: t, topic: t, topics: []string{t}}
+	}
+	return out
+}
+
+//treeItems returns the rows for the topics that start with
+//prefix.  Groups are followed by their children if they are
+//expanded.  A group with a single topic in it is just shown as
+//the topic.
+func treeItems(topics []string, prefix, sep string, depth int, expanded func(string) bool) []rootItem {
+	var names []string
+	members := map[string][]string{}
+	for _, t := range topics {
+		rest := t[len(prefix):]
+		name := rest
+		if i := strings.Index(rest, sep); i > 0 && i < len(rest)-len(sep) {
+			name = rest[:i+len(sep)]
+		}
+
+		if _, ok := members[name]; !ok {
+			names = append(names, name)
+		}
+		members[name] = append(members[name], t)
+	}
+
+	sort.Strings(names)
+
+	indent := strings.Repeat("  ", depth)
+	var out []rootItem
+	for _, name := range names {
+		m := members[name]
+		if len(m) == 1 {
+			out = append(out, rootItem{
+				label:  fmt.Sprintf("%s  %s", indent, m[0][len(prefix):]),
+				topic:  m[0],
+				parent: prefix,
+				topics: m,
+			})
+			continue
+		}
+
+		group := prefix + name
+		sign := "+"
+		if expanded(group) {
+			sign = "-"
+		}
+
+		out = append(out, rootItem{
+			label:  fmt.Sprintf("%s%s %s (%d)", indent, sign, name, len(m)),
+			group:  group,
+			parent: prefix,
+			topics: m,
+		})
+
+		if expanded(group) {
+			out = append(out, treeItems(m, group, sep, depth+1, expanded)...)
+		}
+	}
+	return out
+}
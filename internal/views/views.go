@@ -5,27 +5,60 @@ import (
 	"log"
 	"os"
 
-	"github.com/cswank/kcli/internal/kafka"
+	"github.com/cswank/kcli/internal/output"
+	"github.com/cswank/kcli/pkg/kafka"
 	ui "github.com/jroimartin/gocui"
 )
 
+//Opt changes how the gui is set up
+type Opt func(*screen)
+
+//WithOutput sets the format (see internal/output) that C-p
+//prints in.
+func WithOutput(format string) Opt {
+	return func(s *screen) {
+		s.output = format
+	}
+}
+
+//WithSeparator sets what topic names are split on when they
+//are shown as a tree.
+func WithSeparator(sep string) Opt {
+	return func(s *screen) {
+		s.body.root().separator = sep
+	}
+}
+
+//WithDecoderLoader sets how the decoder command finds a
+//Decoder by name.
+func WithDecoderLoader(f func(string) (kafka.Decoder, error)) Opt {
+	return func(s *screen) {
+		s.loadDecoder = f
+	}
+}
+
 //NewGui creates the command line user inferface and
-//keybindings.
-func NewGui(cli *kafka.Client, topic string, partition, offset int) error {
+//keybindings.  Anything sent on messages is flashed in
+//the footer.
+func NewGui(cli kafka.Reader, topic string, partition, offset int, messages <-chan string, opts ...Opt) error {
 	g, err := ui.NewGui(ui.Output256)
 	if err != nil {
 		return fmt.Errorf("could not create gui: %s", err)
 	}
 
 	w, h := g.Size()
-	opts := getOpts(h-2, topic, partition, offset)
-	s, err := newScreen(cli, g, w, h, opts...)
+	s, err := newScreen(cli, g, w, h, messages, getOpts(h-2, topic, partition, offset)...)
 	if err != nil {
 		g.Close()
 		cli.Close()
 		log.Fatalf("error: %s", err)
 	}
 
+	s.output = output.Raw
+	for _, opt := range opts {
+		opt(s)
+	}
+
 	g.SetManagerFunc(s.getLayout(g, w, h))
 	g.Cursor = true
 	g.InputEsc = true
@@ -57,7 +90,7 @@ func NewGui(cli *kafka.Client, topic string, partition, offset int) error {
 	closed = true
 	g.Close()
 	if s.after != nil {
-		s.after()
+		return s.after()
 	}
 
 	return nil
@@ -0,0 +1,243 @@
+//Package wasm loads kafka message decoders that have been
+//compiled to WebAssembly.  The module runs inside a pure go
+//runtime, so a single .wasm file works with every build of kcli
+//and a misbehaving decoder can't take the process down with it.
+//
+//A decoder module must export its memory and the following
+//functions:
+//
+//    alloc(size u32) u32
+//    decode(topicPtr, topicLen, dataPtr, dataLen u32) u64
+//
+//alloc returns a pointer to size bytes of guest memory that
+//kcli will copy the topic and message into.  decode returns
+//the location of its output packed as ptr<<32 | len.  If the
+//high bit of len is set the output is an error message rather
+//than a decoded value.  If the module also exports
+//
+//    free(ptr, size u32)
+//
+//then kcli calls it once it is done with each buffer.  Modules
+//that want to decode message keys can export
+//
+//    decode_key(topicPtr, topicLen, keyPtr, keyLen u32) u64
+//
+//which works the same way as decode.
+//See examples/plugins/wasm for a decoder written in go.
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	errFlag = 1 << 31
+
+	//timeout is how long a module gets to decode one message
+	//before it is stopped.
+	timeout = 5 * time.Second
+
+	//maxPages limits how much memory a module can grow to (in
+	//64KiB pages, so 256MB).
+	maxPages = 4096
+)
+
+//Decoder implements kafka.Decoder by calling into a
+//WebAssembly module.
+type Decoder struct {
+	lock     sync.Mutex
+	ctx      context.Context
+	pth      string
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	mod      api.Module
+	alloc    api.Function
+	free     api.Function
+}
+
+//New compiles and instantiates the module found at pth.  The
+//module can't use more than maxPages of memory and a call that
+//takes longer than timeout is stopped.
+func New(pth string) (*Decoder, error) {
+	src, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rc := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(maxPages)
+	r := wazero.NewRuntimeWithConfig(ctx, rc)
+
+	// modules built with tinygo or wasip1 expect wasi to be
+	// present.  No filesystem, network or env is exposed to it.
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+
+	compiled, err := r.CompileModule(ctx, src)
+	if err != nil {
+		r.Close(ctx)
+		return nil, fmt.Errorf("could not compile %s: %s", pth, err)
+	}
+
+	d := &Decoder{
+		ctx:      ctx,
+		pth:      pth,
+		runtime:  r,
+		compiled: compiled,
+	}
+
+	if err := d.start(); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+
+	return d, nil
+}
+
+//start instantiates the module.  It is also used to get a
+//fresh instance after one has been stopped for taking too
+//long.
+func (d *Decoder) start() error {
+	cfg := wazero.NewModuleConfig().WithStartFunctions("_initialize")
+	mod, err := d.runtime.InstantiateModule(d.ctx, d.compiled, cfg)
+	if err != nil {
+		return fmt.Errorf("could not instantiate %s: %s", d.pth, err)
+	}
+
+	d.mod = mod
+	d.alloc = mod.ExportedFunction("alloc")
+	d.free = mod.ExportedFunction("free")
+
+	if d.alloc == nil || mod.ExportedFunction("decode") == nil || mod.Memory() == nil {
+		mod.Close(d.ctx)
+		return fmt.Errorf("%s must export memory, alloc and decode", d.pth)
+	}
+	return nil
+}
+
+//Decode passes topic and data to the module's decode function.
+func (d *Decoder) Decode(topic string, data []byte) ([]byte, error) {
+	return d.call("decode", topic, data)
+}
+
+//DecodeKey passes topic and key to the module's decode_key
+//function.  If the module doesn't have one the key is returned
+//as is.
+func (d *Decoder) DecodeKey(topic string, key []byte) ([]byte, error) {
+	return d.call("decode_key", topic, key)
+}
+
+//call runs the function the module exports as name with a
+//deadline.  If the module doesn't export it data is returned
+//as is.  If the module runs out of time wazero closes it, so a
+//new instance is started for the next message.
+func (d *Decoder) call(name, topic string, data []byte) ([]byte, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	fn := d.mod.ExportedFunction(name)
+	if fn == nil {
+		return data, nil
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, timeout)
+	defer cancel()
+
+	val, err := d.doCall(ctx, fn, topic, data)
+	if ctx.Err() == nil {
+		return val, err
+	}
+
+	if err := d.start(); err != nil {
+		return nil, fmt.Errorf("decoder took longer than %s and could not be restarted: %s", timeout, err)
+	}
+	return nil, fmt.Errorf("decoder took longer than %s", timeout)
+}
+
+func (d *Decoder) doCall(ctx context.Context, fn api.Function, topic string, data []byte) ([]byte, error) {
+	tp, err := d.write(ctx, []byte(topic))
+	if err != nil {
+		return nil, err
+	}
+	defer d.release(ctx, tp, uint32(len(topic)))
+
+	dp, err := d.write(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	defer d.release(ctx, dp, uint32(len(data)))
+
+	res, err := fn.Call(ctx, uint64(tp), uint64(len(topic)), uint64(dp), uint64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res) != 1 {
+		return nil, errors.New("decode functions must return a single u64")
+	}
+
+	ptr := uint32(res[0] >> 32)
+	l := uint32(res[0])
+	failed := l&errFlag != 0
+	l &^= errFlag
+
+	out, ok := d.mod.Memory().Read(ptr, l)
+	if !ok {
+		return nil, fmt.Errorf("decode returned out of range memory (%d, %d)", ptr, l)
+	}
+
+	// out is a view into guest memory, so copy it before the
+	// buffer is freed or the memory grows.
+	val := make([]byte, len(out))
+	copy(val, out)
+	d.release(ctx, ptr, l)
+
+	if failed {
+		return nil, errors.New(string(val))
+	}
+
+	return val, nil
+}
+
+//Close releases the runtime and everything compiled with it.
+func (d *Decoder) Close() error {
+	return d.runtime.Close(d.ctx)
+}
+
+func (d *Decoder) write(ctx context.Context, b []byte) (uint32, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	res, err := d.alloc.Call(ctx, uint64(len(b)))
+	if err != nil {
+		return 0, err
+	}
+
+	ptr := uint32(res[0])
+	if !d.mod.Memory().Write(ptr, b) {
+		return 0, fmt.Errorf("alloc returned out of range memory (%d, %d)", ptr, len(b))
+	}
+
+	return ptr, nil
+}
+
+func (d *Decoder) release(ctx context.Context, ptr, size uint32) {
+	if d.free == nil || size == 0 {
+		return
+	}
+	d.free.Call(ctx, uint64(ptr), uint64(size))
+}
@@ -1,58 +1,138 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"plugin"
+	"strconv"
 	"strings"
 
-	"github.com/cswank/kcli/internal/kafka"
+	"github.com/cswank/kcli/internal/fuzzy"
+	"github.com/cswank/kcli/internal/output"
+	"github.com/cswank/kcli/internal/tunnel"
 	"github.com/cswank/kcli/internal/views"
+	"github.com/cswank/kcli/internal/wasm"
+	"github.com/cswank/kcli/pkg/kafka"
 
+	"golang.org/x/term"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
 var (
 	addrs     = kingpin.Flag("addresses", "comma separated list of kafka addresses").Default("localhost:9092").Short('a').Strings()
 	logout    = kingpin.Flag("log", "for debugging, set the log output to a file").Short('l').String()
-	topic     = kingpin.Flag("topic", "go directly to a topic").Short('t').String()
+	topic     = kingpin.Flag("topic", "go directly to a topic (a partial name is fuzzy matched)").Short('t').String()
 	partition = kingpin.Flag("partition", "go directly to a partition of a topic").Short('p').Default("-1").Int()
-	offset    = kingpin.Flag("offset", "go directly to a message").Short('o').Default("-1").Int()
-	decoder   = kingpin.Flag("decoder", "path to a plugin to decode kafka messages").Short('d').String()
+	offset    = kingpin.Flag("offset", "go directly to a message (by its offset)").Short('o').Default("-1").Int()
+	outputFmt = kingpin.Flag("output", "how to print (with C-p or a command): raw, json, jsonl, csv or table").Default(output.Raw).Enum(output.Formats...)
+	separator = kingpin.Flag("separator", "what topic names are split on when they are shown as a tree ('t')").Default(".").Envar("KCLI_SEPARATOR").String()
+	decoder   = kingpin.Flag("decoder", "path to a plugin (.so) or WebAssembly module (.wasm) to decode kafka messages").Short('d').String()
+	sshUser   = kingpin.Flag("ssh-user", "connect to kafka through ssh tunnels as this user (defaults to ~/.ssh/config or $USER)").String()
+	sshPort   = kingpin.Flag("ssh-port", "port of the ssh server(s) used for tunnels (defaults to ~/.ssh/config or 22)").Int()
+	sshHost   = kingpin.Flag("ssh-host", "tunnel through this host (a bastion or a ~/.ssh/config alias) instead of ssh'ing to each kafka host").String()
+	sshKeys   = kingpin.Flag("ssh-key", "private key file for ssh tunnels (can be repeated)").Strings()
+	mock      = kingpin.Flag("mock", "browse made up data instead of a kafka cluster (for demos)").Bool()
+	proxy     = kingpin.Flag("proxy", "connect to kafka through a proxy, socks5://host:port or http://host:port").Envar("KCLI_PROXY").String()
+	unwrap    = kingpin.Flag("unwrap", "remove gzip, zstd, snappy and base64 from messages before decoding them (--no-unwrap to pass them to the decoder as they are)").Default("true").Bool()
+	idle      = kingpin.Flag("idle-timeout", "how long to wait for the first message before deciding a partition has no more (raise it for slow connections)").Default("10s").Duration()
+	brokerMap = kingpin.Flag("broker-map", "connect to a different address than a broker advertises, for example kafka:9092=127.0.0.1:29092 (comma separated or repeated)").Envar("KCLI_BROKER_MAP").Strings()
 	f         *os.File
+	command   string
+)
+
+const (
+	maxChoices = 20
 )
 
 func init() {
-	kingpin.Parse()
+	command = kingpin.Parse()
 }
 
 func main() {
-	cli := connect()
 	setLogout()
-	err := views.NewGui(cli, *topic, *partition, *offset)
+	cli, msgs := connect()
+
+	var err error
+	if command == browseCmd.FullCommand() {
+		if *topic != "" {
+			*topic = resolveTopic(cli, *topic)
+		}
+		err = views.NewGui(cli, *topic, *partition, *offset, msgs, views.WithOutput(*outputFmt), views.WithSeparator(*separator), views.WithDecoderLoader(loadDecoder))
+	} else {
+		err = run(command, cli, msgs)
+	}
+
 	if f != nil {
 		f.Close()
 		log.SetOutput(os.Stderr)
 	}
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 }
 
-func connect() *kafka.Client {
+func connect() (kafka.Reader, <-chan string) {
+	if *mock {
+		return kafka.NewMock(), nil
+	}
+
 	var opts []kafka.Opt
 	if *decoder != "" {
 		dec := getDecoder(*decoder)
-		opts = []kafka.Opt{kafka.WithDecoder(dec)}
+		opts = append(opts, kafka.WithDecoder(dec))
 	}
 
-	cli, err := kafka.New(getAddresses(*addrs), opts...)
+	if *proxy != "" {
+		opts = append(opts, kafka.WithProxy(*proxy))
+	}
+
+	opts = append(opts, kafka.WithIdleTimeout(*idle), kafka.WithUnwrap(*unwrap))
+
+	if len(*brokerMap) > 0 {
+		opts = append(opts, kafka.WithBrokerMap(getBrokerMap(*brokerMap)))
+	}
+
+	addrs, t := getTunnel(getAddresses(*addrs))
+	if t != nil {
+		opts = append(opts, kafka.WithDialer(t))
+	}
+
+	cli, err := kafka.New(addrs, opts...)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
+	}
+
+	if t == nil {
+		return cli, nil
 	}
 
-	return cli
+	return cli, t.Messages()
+}
+
+func getTunnel(addrs []string) ([]string, *tunnel.Tunnel) {
+	if *sshUser == "" && *sshHost == "" && *sshPort == 0 && len(*sshKeys) == 0 {
+		return addrs, nil
+	}
+
+	t := tunnel.New(*sshUser, *sshPort, *sshHost, addrs, tunnel.WithIdentityFiles(*sshKeys...))
+	out, err := t.Connect()
+	if err != nil {
+		fatal(err)
+	}
+
+	return out, t
+}
+
+// fatal makes sure errors that happen before the gui starts
+// make it to the terminal even when logging to a file.
+func fatal(err error) {
+	log.SetOutput(os.Stderr)
+	log.Fatal(err)
 }
 
 func setLogout() {
@@ -78,22 +158,132 @@ func getAddresses(addrs []string) []string {
 	return out
 }
 
+// resolveTopic finds the topic that name fuzzy matches, asking
+// which one was meant if there is more than one.
+func resolveTopic(cli kafka.Reader, name string) string {
+	topics, err := cli.GetTopics()
+	if err != nil {
+		fatal(err)
+	}
+
+	for _, t := range topics {
+		if t == name {
+			return t
+		}
+	}
+
+	matches := fuzzy.Find(name, topics)
+	switch {
+	case len(matches) == 0:
+		fatal(fmt.Errorf("no topic matches '%s'", name))
+	case len(matches) == 1:
+		return matches[0]
+	case len(matches) > maxChoices:
+		matches = matches[:maxChoices]
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		fatal(fmt.Errorf("'%s' matches more than one topic: %s", name, strings.Join(matches, ", ")))
+	}
+
+	fmt.Fprintf(os.Stderr, "'%s' matches more than one topic:\n", name)
+	for i, t := range matches {
+		fmt.Fprintf(os.Stderr, "%3d) %s\n", i+1, t)
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprint(os.Stderr, "which one? ")
+		line, err := in.ReadString('\n')
+		if err != nil {
+			fatal(err)
+		}
+
+		i, err := strconv.Atoi(strings.TrimSpace(line))
+		if err == nil && i > 0 && i <= len(matches) {
+			return matches[i-1]
+		}
+	}
+}
+
+// getBrokerMap parses from=to pairs of broker addresses.
+func getBrokerMap(pairs []string) map[string]string {
+	m := map[string]string{}
+	for _, pair := range getAddresses(pairs) {
+		parts := strings.Split(pair, "=")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			fatal(fmt.Errorf("invalid broker map %q, it should look like kafka:9092=127.0.0.1:29092", pair))
+		}
+		m[parts[0]] = parts[1]
+	}
+	return m
+}
+
 func getDecoder(pth string) kafka.Decoder {
+	dec, err := loadDecoder(pth)
+	if err != nil {
+		fatal(err)
+	}
+	return dec
+}
+
+// loadDecoder opens a decoder plugin (.so) or WebAssembly module
+// (.wasm).  A name that isn't a file, like avro, is looked for as
+// avro.wasm or avro.so in $KCLI_DECODERS (~/.kcli/decoders by
+// default).
+func loadDecoder(name string) (kafka.Decoder, error) {
+	pth, err := findDecoder(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(pth) == ".wasm" {
+		dec, err := wasm.New(pth)
+		if err != nil {
+			return nil, err
+		}
+		return dec, nil
+	}
+
 	plug, err := plugin.Open(pth)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	s, err := plug.Lookup("Decoder")
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	var dec kafka.Decoder
 	dec, ok := s.(kafka.Decoder)
 	if !ok {
-		log.Fatalf("unexpected type from module symbol")
+		return nil, errors.New("unexpected type from module symbol")
 	}
 
-	return dec
+	return dec, nil
+}
+
+func findDecoder(name string) (string, error) {
+	if _, err := os.Stat(name); err == nil || strings.ContainsRune(name, os.PathSeparator) {
+		return name, nil
+	}
+
+	dir := os.Getenv("KCLI_DECODERS")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".kcli", "decoders")
+	}
+
+	for _, ext := range []string{".wasm", ".so"} {
+		pth := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(pth); err == nil {
+			return pth, nil
+		}
+	}
+
+	return "", fmt.Errorf("could not find a decoder called %s in %s", name, dir)
 }
@@ -0,0 +1,145 @@
+package kafka
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+//dialer connects to brokers for sarama.  It rewrites the
+//addresses that brokers advertise (see WithBrokerMap), goes
+//through a proxy if there is one (see WithProxy) and does the
+//tls handshake itself since sarama skips a custom dialer when
+//tls is enabled.
+type dialer struct {
+	brokers map[string]string
+	tls     *tls.Config
+	net     proxy.Dialer
+}
+
+//Dial connects to addr, or to what addr is mapped to.
+func (d *dialer) Dial(network, addr string) (net.Conn, error) {
+	to := addr
+	if a, ok := d.brokers[addr]; ok {
+		to = a
+	}
+
+	conn, err := d.net.Dial(network, to)
+	if err != nil || d.tls == nil {
+		return conn, err
+	}
+
+	// the broker's certificate is for the name it advertises,
+	// not the address it was mapped to.
+	cfg := d.tls.Clone()
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		cfg.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+//proxyDialer returns a dialer that connects through the
+//socks5 or http proxy at addr.
+func proxyDialer(addr string, forward *net.Dialer) (proxy.Dialer, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %s", addr, err)
+	}
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		return proxy.FromURL(u, forward)
+	case "http":
+		return &httpProxy{url: u, forward: forward}, nil
+	default:
+		return nil, fmt.Errorf("invalid proxy %q, it should start with socks5:// or http://", addr)
+	}
+}
+
+//httpProxy tunnels connections through an http proxy with
+//the CONNECT method.
+type httpProxy struct {
+	url     *url.URL
+	forward *net.Dialer
+}
+
+//Dial connects to addr through the proxy.
+func (h *httpProxy) Dial(network, addr string) (net.Conn, error) {
+	host := h.url.Host
+	if h.url.Port() == "" {
+		host = net.JoinHostPort(host, "80")
+	}
+
+	conn, err := h.forward.Dial(network, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.forward.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(h.forward.Timeout))
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+
+	if u := h.url.User; u != nil {
+		pass, _ := u.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(u.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy could not connect to %s: %s", addr, resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return &bufferedConn{Conn: conn, r: r}, nil
+}
+
+//bufferedConn makes sure nothing the proxy sent after its
+//response is lost in the bufio.Reader.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
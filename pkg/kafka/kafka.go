@@ -0,0 +1,640 @@
+//Package kafka is the engine that kcli is built on.  It can be
+//used on its own to read from kafka the same way kcli does:
+//
+//    cli, err := kafka.New([]string{"localhost:9092"}, kafka.WithDecoder(dec))
+//    if err != nil {
+//        return err
+//    }
+//    defer cli.Close()
+//
+//    partitions, err := cli.GetTopic("orders")
+//    ...
+//    // the first page of 20 messages in partition 0
+//    msgs, err := cli.GetPartition(partitions[0], 20, func([]byte) bool { return true })
+//    ...
+//    // the first match in each partition, searched concurrently
+//    matches, err := cli.SearchTopic(partitions, "order-1234", false, func(i, n int64) {})
+//
+//Messages are unwrapped (gzip, zstd, snappy and base64, unless
+//WithUnwrap(false) is passed to New) and then run through the
+//Decoder, so a Decoder plugin written for kcli works here too.
+//Mock implements the same Reader interface as Client for trying
+//code out without a cluster.
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"golang.org/x/net/proxy"
+)
+
+var (
+	errNotEncoded  = errors.New("not encoded")
+	errProxyDialer = errors.New("a proxy can't be used with an ssh tunnel (or any other dialer)")
+)
+
+const (
+	//gap is how long to wait for the next message once they
+	//have started arriving.  A message that doesn't come right
+	//after the one before it is most likely never coming.
+	gap = 2 * time.Second
+)
+
+// Decoder is the interface that is required of plugins
+type Decoder interface {
+	Decode(topic string, data []byte) ([]byte, error)
+}
+
+// KeyDecoder is an optional interface that a Decoder plugin can
+// implement if message keys are encoded too.  Without it keys are
+// shown as is.
+type KeyDecoder interface {
+	DecodeKey(topic string, key []byte) ([]byte, error)
+}
+
+// Reader is the read only API that kcli is built on.  Client
+// reads from a real cluster and Mock makes up its data.
+type Reader interface {
+	GetTopics() ([]string, error)
+	GetTopic(topic string) ([]Partition, error)
+	GetTopicInfo(topic string) (TopicInfo, error)
+	GetPartition(part Partition, end int, f func([]byte) bool) ([]Message, error)
+	SearchTopic(partitions []Partition, s string, firstResult bool, cb func(int64, int64)) ([]Partition, error)
+	Search(info Partition, s string, cb func(i, j int64)) (int64, error)
+	Fetch(info Partition, end int64, cb func(Message)) error
+	Close()
+}
+
+// plainDecoder is the default Decoder
+type plainDecoder struct{}
+
+func (p plainDecoder) Decode(topic string, data []byte) ([]byte, error) { return data, nil }
+
+//Client fetches from kafka
+type Client struct {
+	addrs       []string
+	sarama      sarama.Client
+	cfg         *sarama.Config
+	decoderLock sync.RWMutex
+	decoder     Decoder
+	concurrency int
+	unwrap      bool
+	idle        time.Duration
+	brokers     map[string]string
+	proxy       string
+	dialer      proxy.Dialer
+}
+
+//TopicInfo sums up a topic.  Messages is the number of
+//messages that are still in kafka (End - Start summed over
+//every partition).
+type TopicInfo struct {
+	Topic      string `json:"topic"`
+	Partitions int    `json:"partitions"`
+	Messages   int64  `json:"messages"`
+	Replicas   int    `json:"replicas"`
+}
+
+//Partition holds information about a kafka partition
+type Partition struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Start     int64  `json:"start"`
+	End       int64  `json:"end"`
+	Offset    int64  `json:"offset"`
+	Filter    string `json:"filter"`
+}
+
+//String turns a partition into a string
+func (p *Partition) String() string {
+	d, _ := json.Marshal(p)
+	return string(d)
+}
+
+//Message holds information about a single kafka message.  If
+//the Decoder failed then Value holds the raw message and
+//DecodeError says why (KeyDecodeError is the same for Key and
+//a KeyDecoder).  Encodings lists any compression or base64 that
+//was removed before the message was decoded.
+type Message struct {
+	Partition      Partition `json:"partition"`
+	Key            []byte    `json:"key,omitempty"`
+	Value          []byte    `json:"msg"`
+	Offset         int64     `json:"offset"`
+	Timestamp      time.Time `json:"timestamp"`
+	Encodings      []string  `json:"encodings,omitempty"`
+	DecodeError    string    `json:"decode_error,omitempty"`
+	KeyDecodeError string    `json:"key_decode_error,omitempty"`
+}
+
+// Opt is a func that sets an  attribute on Client
+type Opt func(*Client)
+
+//New returns a kafka Client.
+func New(addrs []string, opts ...Opt) (*Client, error) {
+	cli := &Client{
+		addrs:       addrs,
+		decoder:     &plainDecoder{},
+		concurrency: 20,
+		unwrap:      true,
+		idle:        10 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(cli)
+	}
+
+	cfg, err := cli.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := sarama.NewClient(addrs, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cli.sarama = s
+	cli.cfg = cfg
+	return cli, nil
+}
+
+// Concurrency is used to set the size of the search worker pool
+func Concurrency(j int) func(*Client) {
+	return func(c *Client) {
+		c.concurrency = j
+	}
+}
+
+// WithIdleTimeout is how long to wait for the first message
+// before deciding that the rest of a partition is gone (log
+// compaction and transaction markers leave gaps that are never
+// delivered).  Slow connections need a longer one.  Once
+// messages are arriving the wait for each one after is cut to
+// a couple of seconds.
+func WithIdleTimeout(d time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.idle = d
+	}
+}
+
+// WithUnwrap turns unwrapping (see the package doc) on or
+// off.  It is on by default, off hands messages to the Decoder
+// exactly as they are in kafka.
+func WithUnwrap(on bool) func(*Client) {
+	return func(c *Client) {
+		c.unwrap = on
+	}
+}
+
+// WithDecoder is used to insert a Decoder plugin
+func WithDecoder(d Decoder) func(*Client) {
+	return func(c *Client) {
+		c.decoder = d
+	}
+}
+
+// SetDecoder changes the Decoder that messages are run through
+// from now on (nil goes back to not decoding them).  It waits
+// for messages that are being decoded and then returns the
+// Decoder it replaced, which is no longer in use so it can be
+// closed.
+func (c *Client) SetDecoder(d Decoder) Decoder {
+	if d == nil {
+		d = &plainDecoder{}
+	}
+
+	c.decoderLock.Lock()
+	defer c.decoderLock.Unlock()
+	old := c.decoder
+	c.decoder = d
+	return old
+}
+
+// WithBrokerMap connects to a different address than the one a
+// broker advertises, for example kafka:9092 -> 127.0.0.1:29092
+// for a broker running in docker.
+func WithBrokerMap(m map[string]string) func(*Client) {
+	return func(c *Client) {
+		c.brokers = m
+	}
+}
+
+// WithProxy connects to kafka through a proxy, either
+// socks5://[user:pass@]host:port or http://[user:pass@]host:port
+func WithProxy(addr string) func(*Client) {
+	return func(c *Client) {
+		c.proxy = addr
+	}
+}
+
+// WithDialer connects to brokers with d instead of directly,
+// for example through an ssh tunnel.  It can't be combined
+// with WithProxy.
+func WithDialer(d proxy.Dialer) func(*Client) {
+	return func(c *Client) {
+		c.dialer = d
+	}
+}
+
+func (c *Client) getConfig() (*sarama.Config, error) {
+	cfg := sarama.NewConfig()
+	tlsCfg, err := getTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.dialer != nil && c.proxy != "" {
+		return nil, errProxyDialer
+	}
+
+	if len(c.brokers) > 0 || c.proxy != "" || c.dialer != nil {
+		d := &dialer{brokers: c.brokers, tls: tlsCfg}
+		nd := &net.Dialer{
+			Timeout:   cfg.Net.DialTimeout,
+			KeepAlive: cfg.Net.KeepAlive,
+		}
+
+		d.net = nd
+		if c.dialer != nil {
+			d.net = c.dialer
+		} else if c.proxy != "" {
+			d.net, err = proxyDialer(c.proxy, nd)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		cfg.Net.Proxy.Enable = true
+		cfg.Net.Proxy.Dialer = d
+		return cfg, nil
+	}
+
+	if tlsCfg == nil {
+		return cfg, nil
+	}
+
+	cfg.Net.TLS.Enable = true
+	cfg.Net.TLS.Config = tlsCfg
+	return cfg, nil
+}
+
+func getTLSConfig() (*tls.Config, error) {
+	certFile := os.Getenv("KCLI_CERT_FILE")
+	keyFile := os.Getenv("KCLI_KEY_FILE")
+	caCertFile := os.Getenv("KCLI_CA_CERT_FILE")
+	if certFile == "" || keyFile == "" || caCertFile == "" {
+		return nil, nil
+	}
+
+	cfg := tls.Config{}
+
+	// Load client cert
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return &cfg, err
+	}
+	cfg.Certificates = []tls.Certificate{cert}
+
+	// Load CA cert
+	caCert, err := ioutil.ReadFile(caCertFile)
+	if err != nil {
+		return &cfg, err
+	}
+	caCertPool := x509.NewCertPool()
+	caCertPool.AppendCertsFromPEM(caCert)
+	cfg.RootCAs = caCertPool
+
+	cfg.BuildNameToCertificate()
+	return &cfg, nil
+}
+
+//GetTopics gets topics (duh)
+func (c *Client) GetTopics() ([]string, error) {
+	return c.sarama.Topics()
+}
+
+//GetTopic gets a single kafka topic
+func (c *Client) GetTopic(topic string) ([]Partition, error) {
+	partitions, err := c.sarama.Partitions(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Partition, len(partitions))
+
+	for i, p := range partitions {
+		n, err := c.sarama.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return nil, err
+		}
+
+		o, err := c.sarama.GetOffset(topic, p, sarama.OffsetOldest)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = Partition{
+			Topic:     topic,
+			Partition: p,
+			Start:     o,
+			End:       n,
+			Offset:    o,
+		}
+	}
+	return out, nil
+}
+
+//GetTopicInfo gets the partition count, message total and
+//replication factor of a topic.
+func (c *Client) GetTopicInfo(topic string) (TopicInfo, error) {
+	partitions, err := c.GetTopic(topic)
+	if err != nil {
+		return TopicInfo{}, err
+	}
+
+	info := TopicInfo{Topic: topic, Partitions: len(partitions)}
+	for _, p := range partitions {
+		info.Messages += p.End - p.Start
+	}
+
+	if len(partitions) > 0 {
+		replicas, err := c.sarama.Replicas(topic, partitions[0].Partition)
+		if err != nil {
+			return TopicInfo{}, err
+		}
+		info.Replicas = len(replicas)
+	}
+
+	return info, nil
+}
+
+//GetPartition fetches a kafka partition.  It includes a callback func
+//so that the caller can tell it when to stop consuming.
+func (c *Client) GetPartition(part Partition, end int, f func([]byte) bool) ([]Message, error) {
+	consumer, err := sarama.NewConsumer(c.addrs, c.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pc, err := consumer.ConsumePartition(part.Topic, part.Partition, part.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		consumer.Close()
+		pc.Close()
+	}()
+
+	var out []Message
+
+	var msg *sarama.ConsumerMessage
+	var i, n int
+	var last bool
+	for i < end && !last {
+		select {
+		case msg = <-pc.Messages():
+			if f(msg.Value) {
+				out = append(out, c.decode(msg, part.End))
+				i++
+			}
+			n++
+			last = msg.Offset >= part.End-1
+		case <-time.After(c.wait(n)):
+			//the rest of the partition is gone (compacted) or is
+			//transaction markers, neither of which are delivered
+			last = true
+		}
+	}
+
+	return out, nil
+}
+
+//wait is how long to wait for the next message after n have
+//arrived.
+func (c *Client) wait(n int) time.Duration {
+	if n == 0 || c.idle < gap {
+		return c.idle
+	}
+	return gap
+}
+
+//decode unwraps a message and then runs the Decoder on it.  A
+//message that can't be decoded is kept as is so that it can
+//still be looked at.
+func (c *Client) decode(msg *sarama.ConsumerMessage, end int64) Message {
+	raw, layers, unwrapErr := c.unwrapValue(msg.Value)
+	m := Message{
+		Key:       msg.Key,
+		Value:     raw,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp,
+		Encodings: layers,
+		Partition: Partition{
+			Offset:    msg.Offset,
+			Partition: msg.Partition,
+			Topic:     msg.Topic,
+			End:       end,
+		},
+	}
+
+	var errs []string
+	if unwrapErr != nil {
+		errs = append(errs, unwrapErr.Error())
+	}
+
+	c.decoderLock.RLock()
+	defer c.decoderLock.RUnlock()
+
+	val, err := c.decoder.Decode(msg.Topic, raw)
+	if err != nil {
+		errs = append(errs, err.Error())
+	} else {
+		m.Value = val
+	}
+
+	if kd, ok := c.decoder.(KeyDecoder); ok && len(msg.Key) > 0 {
+		key, err := kd.DecodeKey(msg.Topic, msg.Key)
+		if err != nil {
+			m.KeyDecodeError = err.Error()
+		} else {
+			m.Key = key
+		}
+	}
+
+	m.DecodeError = strings.Join(errs, "; ")
+	return m
+}
+
+func (c *Client) unwrapValue(val []byte) ([]byte, []string, error) {
+	if !c.unwrap {
+		return val, nil, nil
+	}
+	return unwrap(val)
+}
+
+//Close disconnects from kafka
+func (c *Client) Close() {
+	c.sarama.Close()
+}
+
+type searchResult struct {
+	partition Partition
+	offset    int64
+	error     error
+}
+
+//SearchTopic allows the caller to search across all partitions in a topic.
+func (c *Client) SearchTopic(partitions []Partition, s string, firstResult bool, cb func(int64, int64)) ([]Partition, error) {
+	ch := make(chan searchResult)
+	in := make(chan Partition)
+	n := int64(len(partitions))
+
+	//done stops the workers (and the searches they are in the
+	//middle of) once there is nothing more to wait for.
+	done := make(chan struct{})
+	defer close(done)
+
+	stop := func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
+
+	for i := 0; i < c.concurrency; i++ {
+		go func() {
+			for partition := range in {
+				i, err := c.search(partition, s, stop, func(_, _ int64) {})
+				select {
+				case ch <- searchResult{partition: partition, offset: i, error: err}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(in)
+		for _, p := range partitions {
+			select {
+			case in <- p:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var results []Partition
+
+	nResults := len(partitions)
+	if firstResult {
+		nResults = 1
+	}
+
+	var i int64
+	for i = 0; i < n; i++ {
+		r := <-ch
+		cb(i, n)
+		if r.error != nil {
+			return nil, r.error
+		}
+		if r.offset > -1 {
+			r.partition.Offset = r.offset
+			results = append(results, r.partition)
+		}
+		if len(results) == nResults {
+			break
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[j].Partition >= results[i].Partition
+	})
+
+	return results, nil
+}
+
+func (c *Client) search(info Partition, s string, stop func() bool, cb func(int64, int64)) (int64, error) {
+	n := int64(-1)
+	var i int64
+	err := c.consume(info, info.End, func(msg *sarama.ConsumerMessage) bool {
+		cb(i, info.End)
+		val, _, _ := c.unwrapValue(msg.Value)
+		if strings.Contains(string(val), s) {
+			n = msg.Offset
+			return true
+		}
+		i++
+		return stop()
+	})
+
+	return n, err
+}
+
+//Search is for searching for a string in a single kafka partition.
+//It stops at the first match.
+func (c *Client) Search(info Partition, s string, cb func(i, j int64)) (int64, error) {
+	return c.search(info, s, func() bool { return false }, cb)
+}
+
+//Fetch gets all messages in a partition up intil the 'end' offset.
+//Messages that fail to decode are passed to cb with DecodeError set.
+func (c *Client) Fetch(info Partition, end int64, cb func(Message)) error {
+	return c.consume(info, end, func(msg *sarama.ConsumerMessage) bool {
+		cb(c.decode(msg, info.End))
+		return false
+	})
+}
+
+func (c *Client) consume(info Partition, end int64, cb func(*sarama.ConsumerMessage) bool) error {
+	consumer, err := sarama.NewConsumer(c.addrs, c.cfg)
+	if err != nil {
+		return err
+	}
+
+	pc, err := consumer.ConsumePartition(info.Topic, info.Partition, info.Offset)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		consumer.Close()
+		pc.Close()
+	}()
+
+	l := info.End - info.Offset
+	if l < end {
+		end = l
+	}
+
+	for i := int64(0); i < end; i++ {
+		select {
+		case msg := <-pc.Messages():
+			if stop := cb(msg); stop || msg.Offset >= info.End-1 {
+				return nil
+			}
+		case <-time.After(c.wait(int(i))):
+			//offsets aren't contiguous (log compaction and
+			//transaction markers) so there can be fewer
+			//messages than end - offset
+			return nil
+		}
+	}
+
+	return nil
+}
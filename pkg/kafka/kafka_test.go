@@ -0,0 +1,291 @@
+package kafka
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+const testTopic = "orders"
+
+//newTestClient returns a Client connected to a fake broker
+//whose testTopic has a partition for each slice of messages.
+func newTestClient(t *testing.T, partitions [][]string, opts ...Opt) (*Client, func()) {
+	b := sarama.NewMockBroker(t, 1)
+
+	meta := sarama.NewMockMetadataResponse(t).SetBroker(b.Addr(), b.BrokerID())
+	offsets := sarama.NewMockOffsetResponse(t)
+	fetch := sarama.NewMockFetchResponse(t, 100)
+	for i, msgs := range partitions {
+		p := int32(i)
+		meta.SetLeader(testTopic, p, b.BrokerID())
+		offsets.SetOffset(testTopic, p, sarama.OffsetOldest, 0)
+		offsets.SetOffset(testTopic, p, sarama.OffsetNewest, int64(len(msgs)))
+		for o, msg := range msgs {
+			fetch.SetMessage(testTopic, p, int64(o), sarama.StringEncoder(msg))
+		}
+		fetch.SetHighWaterMark(testTopic, p, int64(len(msgs)))
+	}
+
+	b.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": meta,
+		"OffsetRequest":   offsets,
+		"FetchRequest":    fetch,
+	})
+
+	cli, err := New([]string{b.Addr()}, opts...)
+	if err != nil {
+		b.Close()
+		t.Fatal(err)
+	}
+
+	return cli, func() {
+		cli.Close()
+		b.Close()
+	}
+}
+
+func TestGetTopic(t *testing.T) {
+	cli, done := newTestClient(t, [][]string{{"a", "b"}, {"c"}})
+	defer done()
+
+	partitions, err := cli.GetTopic(testTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Partition{
+		{Topic: testTopic, Partition: 0, Start: 0, End: 2},
+		{Topic: testTopic, Partition: 1, Start: 0, End: 1},
+	}
+
+	if !reflect.DeepEqual(partitions, want) {
+		t.Errorf("got %+v, want %+v", partitions, want)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	msgs := []string{"one", "two", "three", "four"}
+
+	testCases := []struct {
+		name   string
+		offset int64
+		end    int64
+		want   []string
+	}{
+		{name: "all", end: 4, want: msgs},
+		{name: "some", end: 2, want: msgs[:2]},
+		{name: "from an offset", offset: 1, end: 4, want: msgs[1:]},
+	}
+
+	cli, done := newTestClient(t, [][]string{msgs})
+	defer done()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Partition{Topic: testTopic, Offset: tc.offset, End: int64(len(msgs))}
+
+			var got []string
+			err := cli.Fetch(p, tc.end, func(msg Message) {
+				got = append(got, string(msg.Value))
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFetchDecodes(t *testing.T) {
+	msgs := []string{string(gzipped(t, []byte("hello"))), "bad"}
+	cli, done := newTestClient(t, [][]string{msgs}, WithDecoder(upperDecoder{}))
+	defer done()
+
+	var got []Message
+	err := cli.Fetch(Partition{Topic: testTopic, End: 2}, 2, func(msg Message) {
+		got = append(got, msg)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d messages, want 2", len(got))
+	}
+
+	if string(got[0].Value) != "HELLO" || !reflect.DeepEqual(got[0].Encodings, []string{"gzip"}) || got[0].DecodeError != "" {
+		t.Errorf("the first message wasn't unwrapped and decoded: %+v", got[0])
+	}
+
+	if string(got[1].Value) != "bad" || got[1].DecodeError != "bad message" {
+		t.Errorf("the second message should have been kept with its error: %+v", got[1])
+	}
+}
+
+func TestSearchTopic(t *testing.T) {
+	partitions := [][]string{
+		{"a", "b", "c"},
+		{"a", "needle", "c", "needle"},
+		{"a", "b", "c", "d", "needle"},
+		{"a"},
+	}
+
+	testCases := []struct {
+		name        string
+		s           string
+		firstResult bool
+		concurrency int
+		want        map[int32]int64
+	}{
+		{name: "every match", s: "needle", concurrency: 20, want: map[int32]int64{1: 1, 2: 4}},
+		{name: "one worker", s: "needle", concurrency: 1, want: map[int32]int64{1: 1, 2: 4}},
+		{name: "no match", s: "haystack", concurrency: 20, want: map[int32]int64{}},
+		{name: "first result", s: "needle", firstResult: true, concurrency: 1, want: map[int32]int64{1: 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cli, done := newTestClient(t, partitions, Concurrency(tc.concurrency))
+			defer done()
+
+			parts, err := cli.GetTopic(testTopic)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var calls int
+			results, err := cli.SearchTopic(parts, tc.s, tc.firstResult, func(i, n int64) {
+				calls++
+				if n != int64(len(parts)) {
+					t.Errorf("got n %d, want %d", n, len(parts))
+				}
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[int32]int64{}
+			for i, r := range results {
+				got[r.Partition] = r.Offset
+				if i > 0 && results[i-1].Partition > r.Partition {
+					t.Errorf("results aren't sorted: %+v", results)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+
+			if calls == 0 {
+				t.Error("the progress callback wasn't called")
+			}
+		})
+	}
+}
+
+func TestSearchTopicError(t *testing.T) {
+	cli, done := newTestClient(t, [][]string{{"a"}, {"b"}, {"c"}}, Concurrency(1))
+	defer done()
+
+	parts, err := cli.GetTopic(testTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts[1].Topic = "missing"
+	_, err = cli.SearchTopic(parts, "z", false, func(_, _ int64) {})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSetDecoder(t *testing.T) {
+	msgs := make([]string, 50)
+	for i := range msgs {
+		msgs[i] = "hello"
+	}
+
+	cli, done := newTestClient(t, [][]string{msgs})
+	defer done()
+
+	errs := make(chan error)
+	go func() {
+		errs <- cli.Fetch(Partition{Topic: testTopic, End: 50}, 50, func(msg Message) {})
+	}()
+
+	old := cli.SetDecoder(upperDecoder{})
+	if _, ok := old.(*plainDecoder); !ok {
+		t.Errorf("got %T, want the default decoder back", old)
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err := cli.Fetch(Partition{Topic: testTopic, End: 50}, 1, func(msg Message) {
+		got = append(got, string(msg.Value))
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, []string{"HELLO"}) {
+		t.Errorf("got %v, want the new decoder to be used", got)
+	}
+
+	if _, ok := cli.SetDecoder(nil).(upperDecoder); !ok {
+		t.Error("expected the upper decoder back")
+	}
+}
+
+type upperDecoder struct{}
+
+func (u upperDecoder) Decode(topic string, data []byte) ([]byte, error) {
+	if bytes.Equal(data, []byte("bad")) {
+		return nil, errors.New("bad message")
+	}
+	return []byte(strings.ToUpper(string(data))), nil
+}
+
+func TestProxyWithDialer(t *testing.T) {
+	_, err := New([]string{"localhost:9092"}, WithProxy("socks5://localhost:1080"), WithDialer(&net.Dialer{}))
+	if err != errProxyDialer {
+		t.Errorf("got %v, want %v", err, errProxyDialer)
+	}
+}
+
+func TestGetPartitionMissingTail(t *testing.T) {
+	cli, done := newTestClient(t, [][]string{{"a", "b", "c"}}, WithIdleTimeout(time.Minute))
+	defer done()
+
+	//the last two offsets are never delivered, like the
+	//transaction markers at the end of a partition
+	start := time.Now()
+	msgs, err := cli.GetPartition(Partition{Topic: testTopic, End: 5}, 20, func([]byte) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 3 {
+		t.Errorf("got %d messages, want 3", len(msgs))
+	}
+
+	if d := time.Since(start); d > 2*gap {
+		t.Errorf("took %s, it should have given up after %s", d, gap)
+	}
+}
@@ -0,0 +1,198 @@
+package kafka
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	mockEpoch = time.Date(2019, 11, 1, 0, 0, 0, 0, time.UTC)
+	names     = []string{"Burl", "Bernetta", "Kelsey", "Lieselotte", "Mechelle", "Migdalia", "Mammie", "Hiroko", "Dalia", "Janelle", "Elyse", "Barb", "Major", "Stacey", "Edda", "Theola", "Queenie", "Deedee", "Marya", "Addie", "Joye", "Klara", "Robbie", "Timika", "Wendy", "Gemma", "Helen", "Yen", "Gena", "Kathlene", "Jule", "Lani", "Enriqueta", "Laci", "Georgie", "Nana", "Kori", "Maryam", "Dominica", "Cheree", "Garnett", "Gearldine", "Branda", "Amada", "Darlena", "Keena", "Rosemary", "Stacia", "Gayla", "So"}
+)
+
+//Mock implements Reader with made up data so that kcli can be
+//demoed (or a gui bug reproduced) without a kafka cluster.  The
+//data is generated from the topic, partition and offset so it
+//stays the same while paging back and forth.
+type Mock struct {
+	lock   sync.Mutex
+	topics map[string][]Partition
+}
+
+//NewMock returns a Mock.
+func NewMock() *Mock {
+	return &Mock{topics: map[string][]Partition{}}
+}
+
+//GetTopics returns the made up topics.
+func (m *Mock) GetTopics() ([]string, error) {
+	return getMockTopics()
+}
+
+//GetTopic returns the made up partitions of topic.
+func (m *Mock) GetTopic(topic string) ([]Partition, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	p, ok := m.topics[topic]
+	if !ok {
+		p, _ = getMockTopic(topic)
+		m.topics[topic] = p
+	}
+
+	out := make([]Partition, len(p))
+	copy(out, p)
+	return out, nil
+}
+
+//GetTopicInfo sums up the made up partitions of topic.
+func (m *Mock) GetTopicInfo(topic string) (TopicInfo, error) {
+	partitions, _ := m.GetTopic(topic)
+	info := TopicInfo{Topic: topic, Partitions: len(partitions), Replicas: 3}
+	for _, p := range partitions {
+		info.Messages += p.End - p.Start
+	}
+	return info, nil
+}
+
+//GetPartition returns up to end messages starting at part.Offset.
+func (m *Mock) GetPartition(part Partition, end int, f func([]byte) bool) ([]Message, error) {
+	return getMockPartition(part, end, f)
+}
+
+//SearchTopic searches each of the partitions.
+func (m *Mock) SearchTopic(partitions []Partition, s string, firstResult bool, cb func(int64, int64)) ([]Partition, error) {
+	var results []Partition
+	n := int64(len(partitions))
+	for i, p := range partitions {
+		cb(int64(i), n)
+		o, _ := mockSearch(p, s)
+		if o == -1 {
+			continue
+		}
+
+		p.Offset = o
+		results = append(results, p)
+		if firstResult {
+			break
+		}
+	}
+	return results, nil
+}
+
+//Search finds the first message at or after info.Offset that
+//contains s.
+func (m *Mock) Search(info Partition, s string, cb func(i, j int64)) (int64, error) {
+	return mockSearch(info, s)
+}
+
+//Fetch passes up to end messages to cb.
+func (m *Mock) Fetch(info Partition, end int64, cb func(Message)) error {
+	return mockFetch(info, end, cb)
+}
+
+//Close does nothing.
+func (m *Mock) Close() {}
+
+func getMockTopics() ([]string, error) {
+	t := []string{
+		"__consumer_offsets",
+		"_schemas",
+		"billing.invoices.created",
+		"billing.invoices.paid",
+		"billing.orders.created",
+		"billing.orders.shipped",
+		"users.profiles-changelog",
+		"users.signups",
+	}
+	for i := 0; i < 10; i++ {
+		t = append(t, fmt.Sprintf("topic %d", i))
+	}
+	return t, nil
+}
+
+func getMockTopic(topic string) ([]Partition, error) {
+	r := mockRand(topic)
+	p := make([]Partition, 100)
+	for i := 0; i < 100; i++ {
+		s := r.Intn(5000)
+		e := r.Intn(5000)
+		p[i] = Partition{Partition: int32(i), Topic: topic, Start: int64(s), End: int64(s + e), Offset: int64(s)}
+	}
+	return p, nil
+}
+
+func getMockPartition(part Partition, num int, f func([]byte) bool) ([]Message, error) {
+	var out []Message
+	for o := part.Offset; o < part.End && len(out) < num; o++ {
+		if mockCompacted(part, o) {
+			continue
+		}
+		msg := getMockMessage(part, o)
+		if f(msg.Value) {
+			out = append(out, msg)
+		}
+	}
+	return out, nil
+}
+
+func getMockMessage(part Partition, offset int64) Message {
+	r := mockRand(fmt.Sprintf("%s/%d/%d", part.Topic, part.Partition, offset))
+	return Message{
+		Key:       []byte(fmt.Sprintf("%d", r.Intn(1000))),
+		Value:     []byte(fmt.Sprintf(`{"name": "%s", "age": %d}`, names[r.Intn(len(names))], 1+r.Intn(100))),
+		Offset:    offset,
+		Timestamp: mockEpoch.Add(time.Duration(offset) * time.Second),
+		Partition: Partition{
+			Partition: part.Partition,
+			Topic:     part.Topic,
+			Start:     part.Start,
+			End:       part.End,
+			Offset:    offset,
+		},
+	}
+}
+
+func mockFetch(info Partition, end int64, cb func(Message)) error {
+	var n int64
+	for o := info.Offset; o < info.End && n < end; o++ {
+		if mockCompacted(info, o) {
+			continue
+		}
+		cb(getMockMessage(info, o))
+		n++
+	}
+
+	return nil
+}
+
+func mockSearch(info Partition, s string) (int64, error) {
+	for o := info.Offset; o < info.End; o++ {
+		if mockCompacted(info, o) {
+			continue
+		}
+		if strings.Contains(string(getMockMessage(info, o).Value), s) {
+			return o, nil
+		}
+	}
+	return int64(-1), nil
+}
+
+//mockCompacted is true for the offsets that are missing from a
+//changelog topic so that the gaps log compaction leaves can be
+//seen without a kafka cluster.
+func mockCompacted(part Partition, offset int64) bool {
+	return strings.HasSuffix(part.Topic, "-changelog") && mockRand(fmt.Sprintf("%s/%d/%d", part.Topic, part.Partition, offset)).Intn(3) > 0
+}
+
+//mockRand returns a rand seeded from s so that the same s
+//always gets the same data.
+func mockRand(s string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
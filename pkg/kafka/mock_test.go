@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var _ Reader = &Mock{}
+
+func TestMockIsRepeatable(t *testing.T) {
+	m := NewMock()
+	partitions, err := m.GetTopic("billing.orders.created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := partitions[3]
+	first, err := m.GetPartition(p, 10, func([]byte) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := NewMock().GetPartition(p, 10, func([]byte) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) == 0 || !reflect.DeepEqual(first, again) {
+		t.Errorf("the same partition should get the same messages, got %v and %v", first, again)
+	}
+}
+
+func TestMockGetTopicIsACopy(t *testing.T) {
+	m := NewMock()
+	partitions, _ := m.GetTopic("users.signups")
+	partitions[0].Offset = -100
+
+	again, _ := m.GetTopic("users.signups")
+	if again[0].Offset == -100 {
+		t.Error("changing what GetTopic returned changed the mock")
+	}
+}
+
+func TestMockGetTopicInfo(t *testing.T) {
+	m := NewMock()
+	partitions, _ := m.GetTopic("users.signups")
+	info, err := m.GetTopicInfo("users.signups")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int64
+	for _, p := range partitions {
+		n += p.End - p.Start
+	}
+
+	if info.Partitions != len(partitions) || info.Messages != n {
+		t.Errorf("got %+v, want %d partitions and %d messages", info, len(partitions), n)
+	}
+}
+
+func TestMockCompacted(t *testing.T) {
+	m := NewMock()
+	partitions, _ := m.GetTopic("users.profiles-changelog")
+	p := partitions[0]
+
+	msgs, err := m.GetPartition(p, 20, func([]byte) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 20 {
+		t.Fatalf("got %d messages, want 20", len(msgs))
+	}
+
+	gaps := msgs[len(msgs)-1].Offset - msgs[0].Offset + 1 - int64(len(msgs))
+	if gaps == 0 {
+		t.Error("a changelog topic should have gaps in its offsets")
+	}
+
+	for i := 1; i < len(msgs); i++ {
+		if msgs[i].Offset <= msgs[i-1].Offset {
+			t.Fatalf("offsets should increase, got %d after %d", msgs[i].Offset, msgs[i-1].Offset)
+		}
+	}
+
+	var fetched []int64
+	err = m.Fetch(p, 20, func(msg Message) {
+		fetched = append(fetched, msg.Offset)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fetched) != 20 || fetched[19] != msgs[19].Offset {
+		t.Errorf("Fetch should count messages, not offsets: got %v", fetched)
+	}
+}
+
+func TestMockSearchTopic(t *testing.T) {
+	m := NewMock()
+	partitions, _ := m.GetTopic("users.signups")
+	partitions = partitions[:10]
+
+	results, err := m.SearchTopic(partitions, "Burl", false, func(_, _ int64) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) == 0 {
+		t.Fatal("expected some results")
+	}
+
+	for _, r := range results {
+		msgs, _ := m.GetPartition(r, 1, func([]byte) bool { return true })
+		if len(msgs) != 1 || msgs[0].Offset != r.Offset || !strings.Contains(string(msgs[0].Value), "Burl") {
+			t.Errorf("partition %d offset %d isn't a match: %v", r.Partition, r.Offset, msgs)
+		}
+	}
+
+	first, err := m.SearchTopic(partitions, "Burl", true, func(_, _ int64) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != 1 || first[0] != results[0] {
+		t.Errorf("got %v, want just %v", first, results[0])
+	}
+}
@@ -0,0 +1,207 @@
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"unicode/utf8"
+
+	snappy "github.com/eapache/go-xerial-snappy"
+	framed "github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// maxLayers keeps a message that is somehow wrapped in itself
+	// from looping forever.
+	maxLayers = 5
+
+	// maxUnwrapped is as big as a message is allowed to get when
+	// it is decompressed so that a small message can't use up
+	// all of the memory.
+	maxUnwrapped = 64 << 20
+)
+
+var (
+	gzipMagic   = []byte{0x1f, 0x8b}
+	zstdMagic   = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	xerialMagic = []byte{0x82, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+	framedMagic = []byte{0xff, 0x06, 0x00, 0x00, 's', 'N', 'a', 'P', 'p', 'Y'}
+
+	zstdOnce    sync.Once
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+
+	errTooBig = fmt.Errorf("unwrapped message is bigger than %d MB", maxUnwrapped>>20)
+)
+
+// unwrap peels off any compression or base64 that a producer
+// applied to a message before sending it.  It returns the
+// unwrapped message and the names of the layers that were
+// removed, outermost first.  A layer that would decompress to
+// more than maxUnwrapped is left on and reported as an error.
+func unwrap(val []byte) ([]byte, []string, error) {
+	var layers []string
+	for i := 0; i < maxLayers; i++ {
+		out, layer, err := unwrapOne(val)
+		if err != nil {
+			return val, layers, fmt.Errorf("%s: %s", layer, err)
+		}
+		if layer == "" {
+			break
+		}
+		val = out
+		layers = append(layers, layer)
+	}
+	return val, layers, nil
+}
+
+// unwrapOne removes the outermost layer.  The only error it
+// returns is errTooBig since any other error just means val
+// wasn't wrapped after all.
+func unwrapOne(val []byte) ([]byte, string, error) {
+	var out []byte
+	var err error
+	var layer string
+
+	switch {
+	case bytes.HasPrefix(val, gzipMagic):
+		layer = "gzip"
+		out, err = gunzip(val)
+	case bytes.HasPrefix(val, zstdMagic):
+		layer = "zstd"
+		out, err = unzstd(val)
+	case bytes.HasPrefix(val, xerialMagic):
+		layer = "snappy"
+		out, err = snappy.Decode(val)
+	case bytes.HasPrefix(val, framedMagic):
+		layer = "snappy"
+		out, err = readAll(framed.NewReader(bytes.NewReader(val)))
+	default:
+		layer = "base64"
+		out, err = unbase64(val)
+	}
+
+	if err == errTooBig {
+		return val, layer, err
+	}
+
+	if err != nil {
+		return val, "", nil
+	}
+	return out, layer, nil
+}
+
+func gunzip(val []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(val))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readAll(r)
+}
+
+// readAll reads r up to maxUnwrapped.
+func readAll(r io.Reader) ([]byte, error) {
+	out, err := ioutil.ReadAll(io.LimitReader(r, maxUnwrapped+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(out) > maxUnwrapped {
+		return nil, errTooBig
+	}
+	return out, nil
+}
+
+func unzstd(val []byte) ([]byte, error) {
+	zstdOnce.Do(func() {
+		zstdDecoder, zstdErr = zstd.NewReader(
+			nil,
+			zstd.WithDecoderLowmem(true),
+			zstd.WithDecoderMaxMemory(maxUnwrapped),
+		)
+	})
+
+	if zstdErr != nil {
+		return nil, zstdErr
+	}
+
+	// the decoder doesn't check the size after the last block,
+	// so out can still be a little too big.
+	out, err := zstdDecoder.DecodeAll(val, nil)
+	if err == zstd.ErrDecoderSizeExceeded || len(out) > maxUnwrapped {
+		return nil, errTooBig
+	}
+	return out, err
+}
+
+// unbase64 only reports success when the decoded message looks
+// like it was meant to be base64: it is json, another layer that
+// can be unwrapped or binary that came from text with the mix of
+// characters base64 produces.  Otherwise plain words that happen
+// to be valid base64 would get mangled.
+func unbase64(val []byte) ([]byte, error) {
+	if len(val) < 8 || !isBase64(val) {
+		return nil, errNotEncoded
+	}
+
+	encodings := []*base64.Encoding{base64.RawStdEncoding, base64.RawURLEncoding}
+	if len(val)%4 == 0 {
+		encodings = []*base64.Encoding{base64.StdEncoding, base64.URLEncoding}
+	}
+
+	for _, enc := range encodings {
+		out, err := enc.DecodeString(string(val))
+		if err != nil {
+			continue
+		}
+
+		if json.Valid(out) || (!utf8.Valid(out) && looksRandom(val)) {
+			return out, nil
+		}
+
+		if _, layer, _ := unwrapOne(out); layer != "" && layer != "base64" {
+			return out, nil
+		}
+	}
+
+	return nil, errNotEncoded
+}
+
+func isBase64(val []byte) bool {
+	for i, c := range val {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '+', c == '/', c == '-', c == '_':
+		case c == '=' && i >= len(val)-2:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func looksRandom(val []byte) bool {
+	if len(val) < 16 {
+		return false
+	}
+
+	var upper, lower, digit bool
+	for _, c := range val {
+		switch {
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	snappy "github.com/eapache/go-xerial-snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestUnwrap(t *testing.T) {
+	msg := []byte(`{"name": "Burl", "age": 63}`)
+
+	testCases := []struct {
+		name   string
+		val    []byte
+		layers []string
+	}{
+		{name: "plain", val: msg},
+		{name: "gzip", val: gzipped(t, msg), layers: []string{"gzip"}},
+		{name: "zstd", val: zstded(t, msg), layers: []string{"zstd"}},
+		{name: "snappy", val: snappy.EncodeStream(nil, msg), layers: []string{"snappy"}},
+		{name: "base64", val: base64ed(msg), layers: []string{"base64"}},
+		{name: "base64 gzip", val: base64ed(gzipped(t, msg)), layers: []string{"base64", "gzip"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, layers, err := unwrap(tc.val)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(out, msg) {
+				t.Errorf("got %q, want %q", out, msg)
+			}
+
+			if !reflect.DeepEqual(layers, tc.layers) {
+				t.Errorf("got layers %v, want %v", layers, tc.layers)
+			}
+		})
+	}
+}
+
+func TestUnwrapNotEncoded(t *testing.T) {
+	testCases := []string{
+		"hello",
+		"ThisIsAWord",
+		"deadbeefcafe",
+		"not base64!",
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc, func(t *testing.T) {
+			out, layers, err := unwrap([]byte(tc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(out) != tc || len(layers) != 0 {
+				t.Errorf("got %q %v, want %q left alone", out, layers, tc)
+			}
+		})
+	}
+}
+
+func TestUnwrapTooBig(t *testing.T) {
+	big := make([]byte, maxUnwrapped+10)
+
+	testCases := []struct {
+		name string
+		val  []byte
+	}{
+		{name: "gzip", val: gzipped(t, big)},
+		{name: "zstd", val: zstded(t, big)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, layers, err := unwrap(tc.val)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if !bytes.Equal(out, tc.val) || len(layers) != 0 {
+				t.Errorf("the message should have been left as it was, got %d bytes %v", len(out), layers)
+			}
+		})
+	}
+}
+
+func gzipped(t *testing.T, val []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(val); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, val []byte) []byte {
+	w, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	return w.EncodeAll(val, nil)
+}
+
+func base64ed(val []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(val))
+}